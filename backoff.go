@@ -0,0 +1,48 @@
+package serial
+
+import "time"
+
+// Backoff paces the retry loop Linux writes spin in while the output buffer is
+// full.
+type Backoff interface {
+	// Next returns how long to wait before retrying.
+	Next() time.Duration
+	// Reset is called once I/O makes progress.
+	Reset()
+}
+
+// defaultBackoff retries every millisecond. It is stateless, so it is shared
+// by all ports.
+var defaultBackoff Backoff = fixedBackoff(time.Millisecond)
+
+type fixedBackoff time.Duration
+
+// NewFixedBackoff returns a Backoff that always waits d.
+func NewFixedBackoff(d time.Duration) Backoff {
+	return fixedBackoff(d)
+}
+
+func (f fixedBackoff) Next() time.Duration { return time.Duration(f) }
+func (f fixedBackoff) Reset()              {}
+
+type exponentialBackoff struct {
+	min, max, next time.Duration
+}
+
+// NewExponentialBackoff returns a Backoff that waits min, doubling the wait on
+// each retry up to max, and drops back to min once I/O makes progress.
+func NewExponentialBackoff(min, max time.Duration) Backoff {
+	return &exponentialBackoff{min: min, max: max, next: min}
+}
+
+func (e *exponentialBackoff) Next() time.Duration {
+	d := e.next
+	if e.next *= 2; e.next > e.max {
+		e.next = e.max
+	}
+	return d
+}
+
+func (e *exponentialBackoff) Reset() {
+	e.next = e.min
+}
@@ -0,0 +1,24 @@
+package serial_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shasderias/serial"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := serial.NewExponentialBackoff(time.Millisecond, 5*time.Millisecond)
+
+	for _, want := range []time.Duration{1, 2, 4, 5, 5} {
+		if got := b.Next(); got != want*time.Millisecond {
+			t.Fatalf("got %v; want %v", got, want*time.Millisecond)
+		}
+	}
+
+	b.Reset()
+
+	if got := b.Next(); got != time.Millisecond {
+		t.Fatalf("got %v after Reset; want %v", got, time.Millisecond)
+	}
+}
@@ -0,0 +1,32 @@
+package serial
+
+// minBaudMismatchSample is the smallest sample LikelyBaudMismatch will judge.
+const minBaudMismatchSample = 16
+
+// LikelyBaudMismatch reports whether sample, read from a port that normally
+// carries text, looks like the noise a baud rate mismatch produces: mostly
+// 0x00 and 0xFF (framing errors and stretched idle bits), other bytes with the
+// high bit set and stray control characters.
+//
+// It is a heuristic for hints such as "wrong baud rate?" in setup tools.
+// Binary protocols look like noise to it, and samples shorter than 16 bytes
+// are never flagged.
+func LikelyBaudMismatch(sample []byte) bool {
+	if len(sample) < minBaudMismatchSample {
+		return false
+	}
+
+	var noise int
+	for _, b := range sample {
+		switch {
+		case b == 0x00 || b == 0xff:
+			noise += 2 // the strongest signs of mismatched framing
+		case b >= 0x80:
+			noise++
+		case b < 0x20 && b != '\t' && b != '\n' && b != '\r':
+			noise++
+		}
+	}
+
+	return noise >= len(sample)
+}
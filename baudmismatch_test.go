@@ -0,0 +1,32 @@
+package serial_test
+
+import (
+	"testing"
+
+	"github.com/shasderias/serial"
+)
+
+func TestLikelyBaudMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample []byte
+		want   bool
+	}{
+		{"text", []byte("$GPGLL,4916.45,N,12311.12,W,225444,A*31\r\n"), false},
+		{"short noise", []byte{0x00, 0xff, 0x80, 0xfe}, false},
+		{"framing noise", []byte{
+			0x00, 0xff, 0xf8, 0x00, 0x80, 0xe0, 0xff, 0x00,
+			0x1c, 0xfc, 0x00, 0x00, 0xf0, 0x78, 0xff, 0x86,
+		}, true},
+		{"high bit noise", []byte{
+			0x9c, 0xe6, 0x86, 0xf3, 0xbe, 0x98, 0xe0, 0xa6,
+			0x93, 0xf8, 0xc2, 0x8e, 0xe3, 0xfa, 0xb8, 0x9e,
+		}, true},
+	}
+
+	for _, tt := range tests {
+		if got := serial.LikelyBaudMismatch(tt.sample); got != tt.want {
+			t.Errorf("%s: got %t; want %t", tt.name, got, tt.want)
+		}
+	}
+}
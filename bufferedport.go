@@ -0,0 +1,200 @@
+package serial
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// BufferedPort reads a Port from a background goroutine into a ring buffer,
+// so a protocol loop can select on Ready alongside other events instead of
+// blocking in Read or polling. The goroutine stops when the Port is closed or
+// a read fails with anything other than os.ErrDeadlineExceeded.
+//
+// Once the buffer is full the goroutine stops reading until bytes are
+// consumed, leaving further input to the driver's buffer. The Port should not
+// be read from by other means while it is in use. The BufferedPort owns the
+// Port's read deadline: it clears it when started and whenever it expires, so
+// the goroutine waits for input rather than spinning on a past deadline.
+type BufferedPort struct {
+	p Port
+
+	mut    sync.Mutex
+	cond   *sync.Cond // broadcast when bytes are added or consumed, or the reader stops
+	buf    []byte
+	start  int   // buffered bytes start at buf[start] and may wrap around
+	n      int   // number of buffered bytes
+	err    error // error that stopped the reader, returned once buf is drained
+	closed bool  // Close was called
+
+	ready chan struct{} // holds a value while bytes are buffered or err is set
+	done  chan struct{} // closed when the reader has stopped
+}
+
+// NewBufferedPort starts reading p into a buffer of bufSize bytes. It panics
+// if bufSize is not positive.
+func NewBufferedPort(p Port, bufSize int) *BufferedPort {
+	if bufSize <= 0 {
+		panic("serial: non-positive buffer size")
+	}
+
+	b := &BufferedPort{
+		p:     p,
+		buf:   make([]byte, bufSize),
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	b.cond = sync.NewCond(&b.mut)
+	p.SetReadDeadline(time.Time{})
+	go b.run()
+	return b
+}
+
+// Ready returns a channel that yields a value while bytes are buffered or the
+// reader has stopped, i.e. while Read would not block. With a single goroutine
+// reading, a receive is always followed by a Read that returns immediately.
+func (b *BufferedPort) Ready() <-chan struct{} {
+	return b.ready
+}
+
+// Buffered returns the number of bytes that can be read without blocking.
+func (b *BufferedPort) Buffered() int {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.n
+}
+
+// Read reads buffered bytes into p, waiting for some to arrive if none are
+// buffered. Once the reader has stopped and the buffer is drained, Read
+// returns the error that stopped it, ErrPortClosed if the Port was closed.
+func (b *BufferedPort) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	for b.n == 0 && b.err == nil {
+		b.cond.Wait()
+	}
+	if b.n == 0 {
+		return 0, b.err
+	}
+
+	end := b.start + b.n
+	if end > len(b.buf) {
+		end = len(b.buf)
+	}
+	n := copy(p, b.buf[b.start:end])
+	if n < len(p) && n < b.n {
+		n += copy(p[n:], b.buf[:b.n-n])
+	}
+
+	b.start = (b.start + n) % len(b.buf)
+	b.n -= n
+	b.updateReady()
+	b.cond.Broadcast()
+	return n, nil
+}
+
+// Write writes p to the Port.
+func (b *BufferedPort) Write(p []byte) (int, error) {
+	return b.p.Write(p)
+}
+
+// Close closes the Port and waits for the reader to stop. Bytes still
+// buffered can be read afterwards.
+func (b *BufferedPort) Close() error {
+	err := b.p.Close()
+
+	b.mut.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mut.Unlock()
+
+	<-b.done
+	return err
+}
+
+func (b *BufferedPort) run() {
+	defer close(b.done)
+
+	for {
+		b.mut.Lock()
+		for b.n == len(b.buf) && !b.closed {
+			b.cond.Wait()
+		}
+		if b.closed {
+			b.stop(ErrPortClosed)
+			b.mut.Unlock()
+			return
+		}
+
+		// Read into the free space following the buffered bytes; Read only
+		// touches buffered bytes, so this needs no lock.
+		if b.n == 0 {
+			b.start = 0
+		}
+		var free []byte
+		if end := b.start + b.n; end < len(b.buf) {
+			free = b.buf[end:]
+		} else {
+			free = b.buf[end-len(b.buf) : b.start]
+		}
+		b.mut.Unlock()
+
+		// Port.Read fills free, so wait for one byte, then take whatever else
+		// has arrived without waiting.
+		n, err := b.p.Read(free[:1])
+		if err == nil && len(free) > 1 {
+			var m int
+			m, err = b.p.TryRead(free[1:])
+			n += m
+		}
+
+		if err == os.ErrDeadlineExceeded {
+			// set by someone else; a deadline left in the past would fail
+			// every read at once
+			err = b.p.SetReadDeadline(time.Time{})
+		}
+
+		b.mut.Lock()
+		b.n += n
+		if err != nil {
+			b.stop(err)
+			b.mut.Unlock()
+			return
+		}
+		if n > 0 {
+			b.updateReady()
+			b.cond.Broadcast()
+		}
+		b.mut.Unlock()
+	}
+}
+
+// stop must be called with b.mut held.
+func (b *BufferedPort) stop(err error) {
+	b.err = err
+	b.updateReady()
+	b.cond.Broadcast()
+}
+
+// updateReady must be called with b.mut held. It keeps a value in b.ready
+// exactly while Read would not block.
+func (b *BufferedPort) updateReady() {
+	if b.n > 0 || b.err != nil {
+		select {
+		case b.ready <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	select {
+	case <-b.ready:
+	default:
+	}
+}
@@ -0,0 +1,111 @@
+package serial_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/shasderias/serial"
+)
+
+func TestBufferedPort(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+
+	b := serial.NewBufferedPort(port2, 64)
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-b.Ready():
+	case <-time.After(longSleepDuration):
+		t.Fatal("no data ready")
+	}
+
+	buf := make([]byte, len(testString))
+	if _, err := io.ReadFull(b, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != testString {
+		t.Fatalf("got %q; want %q", buf, testString)
+	}
+
+	select {
+	case <-b.Ready():
+		t.Fatal("ready with no data buffered")
+	default:
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-b.Ready()
+	if _, err := b.Read(buf); err != serial.ErrPortClosed {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestBufferedPortWraps(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+
+	b := serial.NewBufferedPort(port2, 5)
+	defer b.Close()
+
+	want := testString + testString
+	if _, err := port1.Write([]byte(want)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, len(want))
+	for i := 0; i < len(buf); {
+		end := i + 3
+		if end > len(buf) {
+			end = len(buf)
+		}
+		n, err := b.Read(buf[i:end])
+		if err != nil {
+			t.Fatal(err)
+		}
+		i += n
+	}
+	if string(buf) != want {
+		t.Fatalf("got %q; want %q", buf, want)
+	}
+}
+
+func TestBufferedPortPastDeadline(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+
+	if err := port2.SetReadDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	b := serial.NewBufferedPort(port2, 64)
+	defer b.Close()
+
+	time.Sleep(shortSleepDuration)
+	if err := port2.SetReadDeadline(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// the expired deadline was cleared rather than failing read after read
+	if timeouts := port2.Stats().ReadTimeouts; timeouts > 1 {
+		t.Fatalf("got %d read timeouts; want the deadline cleared", timeouts)
+	}
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len(testString))
+	if _, err := io.ReadFull(b, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != testString {
+		t.Fatalf("got %q; want %q", buf, testString)
+	}
+}
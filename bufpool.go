@@ -0,0 +1,37 @@
+package serial
+
+import "sync"
+
+// maxPooledBuffer is the largest buffer PutBuffer keeps for reuse, so a one
+// off large read does not pin memory.
+const maxPooledBuffer = 64 * 1024
+
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// GetBuffer returns a buffer of length size from a pool shared by the package,
+// for high frequency readers that would otherwise allocate a buffer per Read.
+//
+// The caller owns the buffer until it hands it back with PutBuffer, after
+// which neither the buffer nor any slice of it may be used. Its contents are
+// not cleared between uses.
+func GetBuffer(size int) *[]byte {
+	b := bufPool.Get().(*[]byte)
+	if cap(*b) < size {
+		*b = make([]byte, size)
+	}
+	*b = (*b)[:size]
+	return b
+}
+
+// PutBuffer returns b, obtained from GetBuffer, to the pool.
+func PutBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
+	bufPool.Put(b)
+}
@@ -0,0 +1,17 @@
+package serial_test
+
+import (
+	"testing"
+
+	"github.com/shasderias/serial"
+)
+
+func TestBufferPool(t *testing.T) {
+	for _, size := range []int{0, 16, 1024, 128 * 1024} {
+		b := serial.GetBuffer(size)
+		if len(*b) != size {
+			t.Fatalf("got buffer of length %d; want %d", len(*b), size)
+		}
+		serial.PutBuffer(b)
+	}
+}
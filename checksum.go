@@ -0,0 +1,102 @@
+package serial
+
+import "io"
+
+// Checksum identifies a checksum algorithm commonly used to protect frames
+// sent over serial links.
+type Checksum int
+
+const (
+	ChecksumCRC16Modbus Checksum = iota // CRC-16/MODBUS, as used by Modbus RTU
+	ChecksumCRC16CCITT                  // CRC-16/CCITT-FALSE
+	ChecksumXOR                         // XOR of all bytes, as used by NMEA 0183
+	ChecksumLRC                         // two's complement of the byte sum, as used by Modbus ASCII
+)
+
+// Sum returns the checksum of b.
+func (c Checksum) Sum(b []byte) uint16 {
+	return c.final(c.update(c.initial(), b))
+}
+
+func (c Checksum) initial() uint16 {
+	switch c {
+	case ChecksumCRC16Modbus, ChecksumCRC16CCITT:
+		return 0xffff
+	default:
+		return 0
+	}
+}
+
+func (c Checksum) update(sum uint16, b []byte) uint16 {
+	switch c {
+	case ChecksumCRC16Modbus:
+		for _, v := range b {
+			sum ^= uint16(v)
+			for i := 0; i < 8; i++ {
+				if sum&0x0001 != 0 {
+					sum = sum>>1 ^ 0xa001 // 0x8005 reflected
+				} else {
+					sum >>= 1
+				}
+			}
+		}
+	case ChecksumCRC16CCITT:
+		for _, v := range b {
+			sum ^= uint16(v) << 8
+			for i := 0; i < 8; i++ {
+				if sum&0x8000 != 0 {
+					sum = sum<<1 ^ 0x1021
+				} else {
+					sum <<= 1
+				}
+			}
+		}
+	case ChecksumXOR:
+		for _, v := range b {
+			sum ^= uint16(v)
+		}
+	case ChecksumLRC:
+		for _, v := range b {
+			sum = (sum + uint16(v)) & 0xff
+		}
+	}
+	return sum
+}
+
+func (c Checksum) final(sum uint16) uint16 {
+	if c == ChecksumLRC {
+		return -sum & 0xff
+	}
+	return sum
+}
+
+// ChecksumReader accumulates a running checksum over the bytes read through
+// it, so a frame parser can validate a frame without walking its buffer again.
+type ChecksumReader struct {
+	r    io.Reader
+	kind Checksum
+	sum  uint16
+}
+
+// NewChecksumReader returns a ChecksumReader that reads from r, typically a
+// Port, and checksums the bytes read using kind.
+func NewChecksumReader(r io.Reader, kind Checksum) *ChecksumReader {
+	return &ChecksumReader{r: r, kind: kind, sum: kind.initial()}
+}
+
+func (c *ChecksumReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.sum = c.kind.update(c.sum, b[:n])
+	return n, err
+}
+
+// Sum returns the checksum of the bytes read since the reader was created or
+// last reset.
+func (c *ChecksumReader) Sum() uint16 {
+	return c.kind.final(c.sum)
+}
+
+// Reset discards the running checksum, typically at the start of a frame.
+func (c *ChecksumReader) Reset() {
+	c.sum = c.kind.initial()
+}
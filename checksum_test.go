@@ -0,0 +1,56 @@
+package serial_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/shasderias/serial"
+)
+
+func TestChecksumSum(t *testing.T) {
+	check := []byte("123456789")
+
+	tests := []struct {
+		kind serial.Checksum
+		data []byte
+		want uint16
+	}{
+		{serial.ChecksumCRC16Modbus, check, 0x4b37},
+		{serial.ChecksumCRC16Modbus, []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0a}, 0xcdc5},
+		{serial.ChecksumCRC16CCITT, check, 0x29b1},
+		{serial.ChecksumXOR, check, 0x31},
+		{serial.ChecksumLRC, check, 0x23},
+	}
+
+	for _, tt := range tests {
+		if got := tt.kind.Sum(tt.data); got != tt.want {
+			t.Errorf("checksum %d of %q = %#04x; want %#04x", tt.kind, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestChecksumReader(t *testing.T) {
+	frame := []byte("123456789")
+	r := serial.NewChecksumReader(bytes.NewReader(append(frame, frame...)), serial.ChecksumCRC16Modbus)
+
+	// read in uneven chunks to check that the running sum is carried across reads
+	buf := make([]byte, len(frame))
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(r, buf[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Sum(); got != 0x4b37 {
+		t.Fatalf("got %#04x; want %#04x", got, 0x4b37)
+	}
+
+	r.Reset()
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Sum(); got != 0x4b37 {
+		t.Fatalf("got %#04x after Reset; want %#04x", got, 0x4b37)
+	}
+}
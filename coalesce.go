@@ -0,0 +1,152 @@
+package serial
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// CoalescingWriter batches small writes to cut per-write syscall overhead.
+// Buffered bytes are written once size bytes are pending, once interval has
+// passed since the first pending write, or on Flush, whichever comes first, so
+// interval bounds how long a byte waits before it is handed to the Port.
+// Byte order is preserved and writes to the underlying Port are subject to its
+// write deadline. It is safe for concurrent use.
+type CoalescingWriter struct {
+	w        io.WriteCloser
+	size     int
+	interval time.Duration
+
+	mut      sync.Mutex
+	buf      []byte
+	timer    *time.Timer
+	timerGen uint64 // identifies timer, so a stale timer can tell it is stale
+	err      error  // error from a timed flush, returned by the next call
+
+	stats CoalescingStats
+}
+
+// CoalescingStats counts the flushes a CoalescingWriter has made, and the
+// bytes they wrote, by what triggered them.
+type CoalescingStats struct {
+	SizeFlushes, SizeBytes         int // size bytes were pending
+	TimerFlushes, TimerBytes       int // interval passed
+	ExplicitFlushes, ExplicitBytes int // Flush or Close was called
+}
+
+// NewCoalescingWriter returns a CoalescingWriter that writes to w, typically
+// a Port. It panics if size is not positive.
+func NewCoalescingWriter(w io.WriteCloser, size int, interval time.Duration) *CoalescingWriter {
+	if size <= 0 {
+		panic("serial: non-positive buffer size")
+	}
+	return &CoalescingWriter{
+		w:        w,
+		size:     size,
+		interval: interval,
+		buf:      make([]byte, 0, size),
+	}
+}
+
+// Write buffers b, flushing if size bytes are pending. If the flush fails, the
+// bytes remain buffered and are retried by the next flush.
+func (c *CoalescingWriter) Write(b []byte) (int, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if err := c.takeErr(); err != nil {
+		return 0, err
+	}
+
+	c.buf = append(c.buf, b...)
+
+	if len(c.buf) >= c.size {
+		return len(b), c.flush(&c.stats.SizeFlushes, &c.stats.SizeBytes)
+	}
+
+	if c.timer == nil {
+		c.timerGen++
+		gen := c.timerGen
+		c.timer = time.AfterFunc(c.interval, func() { c.timedFlush(gen) })
+	}
+
+	return len(b), nil
+}
+
+// Flush writes any buffered bytes to the underlying Port.
+func (c *CoalescingWriter) Flush() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if err := c.takeErr(); err != nil {
+		return err
+	}
+	return c.flush(&c.stats.ExplicitFlushes, &c.stats.ExplicitBytes)
+}
+
+// SetLimits changes the number of pending bytes and the time since the first
+// pending write that trigger a flush. A running interval keeps its original
+// length; the new interval applies from the next pending write.
+func (c *CoalescingWriter) SetLimits(size int, interval time.Duration) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.size, c.interval = size, interval
+}
+
+// Stats returns the flushes made so far.
+func (c *CoalescingWriter) Stats() CoalescingStats {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.stats
+}
+
+// Close flushes any buffered bytes, then closes the underlying Port.
+func (c *CoalescingWriter) Close() error {
+	flushErr := c.Flush()
+
+	if err := c.w.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+func (c *CoalescingWriter) timedFlush(gen uint64) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.timer == nil || c.timerGen != gen {
+		return // flushed by other means since the timer fired
+	}
+
+	if err := c.flush(&c.stats.TimerFlushes, &c.stats.TimerBytes); err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+// flush must be called with c.mut held. It adds to the flush and byte counts
+// of its trigger.
+func (c *CoalescingWriter) flush(flushes, bytes *int) error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	if len(c.buf) == 0 {
+		return nil
+	}
+
+	n, err := c.w.Write(c.buf)
+	*flushes++
+	*bytes += n
+	c.buf = c.buf[:copy(c.buf, c.buf[n:])]
+	return err
+}
+
+// takeErr must be called with c.mut held.
+func (c *CoalescingWriter) takeErr() error {
+	err := c.err
+	c.err = nil
+	return err
+}
@@ -0,0 +1,128 @@
+package serial_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shasderias/serial"
+)
+
+// recordingPort records each write made to it.
+type recordingPort struct {
+	mut    sync.Mutex
+	writes [][]byte
+	closed bool
+}
+
+func (r *recordingPort) Write(b []byte) (int, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	r.writes = append(r.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (r *recordingPort) Close() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	r.closed = true
+	return nil
+}
+
+func (r *recordingPort) written() (writes int, data []byte) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	return len(r.writes), bytes.Join(r.writes, nil)
+}
+
+func TestCoalescingWriterFlushesOnSize(t *testing.T) {
+	var rec recordingPort
+	w := serial.NewCoalescingWriter(&rec, 8, time.Hour)
+
+	for _, s := range []string{"abc", "def", "gh", "ij"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, data := rec.written(); n != 1 || string(data) != "abcdefgh" {
+		t.Fatalf("got %d writes of %q; want 1 write of %q", n, data, "abcdefgh")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, data := rec.written(); n != 2 || string(data) != "abcdefghij" || !rec.closed {
+		t.Fatalf("got %d writes of %q, closed %t; want 2 writes of %q, closed", n, data, rec.closed, "abcdefghij")
+	}
+}
+
+func TestCoalescingWriterFlushesOnInterval(t *testing.T) {
+	var rec recordingPort
+	w := serial.NewCoalescingWriter(&rec, 1024, 20*time.Millisecond)
+
+	for _, s := range []string{"hello", " ", "world"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, _ := rec.written(); n != 0 {
+		t.Fatalf("got %d writes before the interval elapsed; want 0", n)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n, data := rec.written(); n != 1 || string(data) != testString {
+		t.Fatalf("got %d writes of %q; want 1 write of %q", n, data, testString)
+	}
+}
+
+func TestCoalescingWriterStats(t *testing.T) {
+	var rec recordingPort
+	w := serial.NewCoalescingWriter(&rec, 4, time.Hour)
+
+	if _, err := w.Write([]byte("abcd")); err != nil {
+		t.Fatal(err)
+	}
+
+	w.SetLimits(1024, 20*time.Millisecond)
+	if _, err := w.Write([]byte("ef")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := w.Write([]byte("g")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := serial.CoalescingStats{
+		SizeFlushes: 1, SizeBytes: 4,
+		TimerFlushes: 1, TimerBytes: 2,
+		ExplicitFlushes: 1, ExplicitBytes: 1,
+	}
+	if got := w.Stats(); got != want {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+}
+
+func TestCoalescingWriterRejectsNonPositiveSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewCoalescingWriter(%d) did not panic", size)
+				}
+			}()
+			serial.NewCoalescingWriter(&recordingPort{}, size, time.Hour)
+		}()
+	}
+}
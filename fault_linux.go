@@ -0,0 +1,99 @@
+//go:build faultinject
+
+package serial
+
+import (
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// FaultHooks replace the system calls ports are opened, read and written
+// with, so tests can simulate errors such as EBUSY, EIO on disconnect, EAGAIN
+// or partial writes without hardware. A nil hook makes the real system call.
+// Only available in builds tagged faultinject.
+type FaultHooks struct {
+	Open  func(path string, mode int) (int, error)
+	Read  func(fd int, b []byte) (int, error)
+	Write func(fd int, b []byte) (int, error)
+
+	// LineErrors replaces TIOCGICOUNT, returning the line errors the driver
+	// has counted since it was loaded, as the ioctl does.
+	LineErrors func(fd int) (LineErrorCounts, error)
+}
+
+var (
+	faultHooks    FaultHooks
+	faultHooksMut sync.RWMutex
+)
+
+// SetFaultHooks installs h for all ports and returns a function that removes
+// it again.
+func SetFaultHooks(h FaultHooks) (restore func()) {
+	faultHooksMut.Lock()
+	defer faultHooksMut.Unlock()
+
+	prev := faultHooks
+	faultHooks = h
+
+	return func() {
+		faultHooksMut.Lock()
+		defer faultHooksMut.Unlock()
+
+		faultHooks = prev
+	}
+}
+
+func sysOpen(path string, mode int) (int, error) {
+	faultHooksMut.RLock()
+	hook := faultHooks.Open
+	faultHooksMut.RUnlock()
+
+	if hook != nil {
+		return hook(path, mode)
+	}
+	return unix.Open(path, mode, 0)
+}
+
+func sysRead(fd int, b []byte) (int, error) {
+	faultHooksMut.RLock()
+	hook := faultHooks.Read
+	faultHooksMut.RUnlock()
+
+	if hook != nil {
+		return hook(fd, b)
+	}
+	return unix.Read(fd, b)
+}
+
+func sysWrite(fd int, b []byte) (int, error) {
+	faultHooksMut.RLock()
+	hook := faultHooks.Write
+	faultHooksMut.RUnlock()
+
+	if hook != nil {
+		return hook(fd, b)
+	}
+	return unix.Write(fd, b)
+}
+
+func sysGetICounter(fd int) (serialICounter, error) {
+	faultHooksMut.RLock()
+	hook := faultHooks.LineErrors
+	faultHooksMut.RUnlock()
+
+	if hook == nil {
+		return getICounter(fd)
+	}
+
+	c, err := hook(fd)
+	if err != nil {
+		return serialICounter{}, err
+	}
+	return serialICounter{
+		Parity:  int32(c.Parity),
+		Frame:   int32(c.Framing),
+		Overrun: int32(c.Overrun),
+		Brk:     int32(c.Break),
+	}, nil
+}
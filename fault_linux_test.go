@@ -0,0 +1,312 @@
+//go:build faultinject
+
+package serial_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shasderias/serial"
+	"golang.org/x/sys/unix"
+)
+
+func TestFaultOpenBusy(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	defer serial.SetFaultHooks(serial.FaultHooks{
+		Open: func(path string, mode int) (int, error) { return -1, unix.EBUSY },
+	})()
+
+	_, err := serial.Open(portPath)
+	if !errors.Is(err, serial.ErrPortInUse) || !errors.Is(err, unix.EBUSY) {
+		t.Fatalf("got %v; want %v wrapping %v", err, serial.ErrPortInUse, unix.EBUSY)
+	}
+}
+
+func TestFaultReadError(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	defer serial.SetFaultHooks(serial.FaultHooks{
+		Read: func(fd int, b []byte) (int, error) { return 0, unix.ENOMEM },
+	})()
+
+	if _, err := port1.Read(make([]byte, 1)); !errors.Is(err, unix.ENOMEM) {
+		t.Fatalf("got %v; want %v", err, unix.ENOMEM)
+	}
+}
+
+func TestFaultLineErrors(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	var (
+		mut    sync.Mutex
+		counts serial.LineErrorCounts
+	)
+	defer serial.SetFaultHooks(serial.FaultHooks{
+		LineErrors: func(fd int) (serial.LineErrorCounts, error) {
+			mut.Lock()
+			defer mut.Unlock()
+			return counts, nil
+		},
+	})()
+
+	// the hook reports no errors yet, so this takes it as the baseline
+	if _, err := port1.LineErrors(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := serial.LineErrorCounts{Parity: 2, Framing: 1, Overrun: 3, Break: 1}
+	mut.Lock()
+	counts = want
+	mut.Unlock()
+
+	c, err := port1.LineErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != want {
+		t.Fatalf("got %+v; want %+v", c, want)
+	}
+
+	c, err = port1.LineErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (serial.LineErrorCounts{}) {
+		t.Fatalf("got %+v on a second call; want no new errors", c)
+	}
+}
+
+func TestFaultHangupIsEOF(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	for _, fault := range []error{unix.EIO, nil} {
+		restore := serial.SetFaultHooks(serial.FaultHooks{
+			Read: func(fd int, b []byte) (int, error) { return 0, fault },
+		})
+
+		if _, err := port1.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("fault %v: got %v; want %v", fault, err, io.EOF)
+		}
+		restore()
+	}
+}
+
+func TestFaultDisconnectWrapsErrno(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	// open the port through a link that can be removed, as a device node is
+	// when its USB adapter is unplugged
+	link := filepath.Join(t.TempDir(), "ttyUSB0")
+	if err := os.Symlink(portPath, link); err != nil {
+		t.Fatal(err)
+	}
+	port, err := serial.Open(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	defer serial.SetFaultHooks(serial.FaultHooks{
+		Read: func(fd int, b []byte) (int, error) { return 0, unix.EIO },
+	})()
+
+	_, err = port.Read(make([]byte, 1))
+	if !errors.Is(err, serial.ErrPortDisconnected) || !errors.Is(err, unix.EIO) {
+		t.Fatalf("got %v; want %v wrapping %v", err, serial.ErrPortDisconnected, unix.EIO)
+	}
+}
+
+func TestFaultPartialWritesAndEAGAIN(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	var eagains int
+	defer serial.SetFaultHooks(serial.FaultHooks{
+		Write: func(fd int, b []byte) (int, error) {
+			if eagains < 3 {
+				eagains++
+				return 0, unix.EAGAIN
+			}
+			return unix.Write(fd, b[:1])
+		},
+	})()
+
+	n, err := port1.Write([]byte(testString))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(testString) {
+		t.Fatalf("%d bytes written; want %d", n, len(testString))
+	}
+
+	buf := make([]byte, len(testString))
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port2.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != testString {
+		t.Fatalf("read %q; want %q", buf, testString)
+	}
+}
+
+func TestFaultWriteErrorAfterPartialWrite(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	const sent = 3
+	var written int
+	defer serial.SetFaultHooks(serial.FaultHooks{
+		Write: func(fd int, b []byte) (int, error) {
+			if written == sent {
+				return 0, unix.ENOMEM
+			}
+			written++
+			return unix.Write(fd, b[:1])
+		},
+	})()
+
+	n, err := port1.Write([]byte(testString))
+	if n != sent || !errors.Is(err, unix.ENOMEM) {
+		t.Fatalf("got %d, %v; want %d, %v", n, err, sent, unix.ENOMEM)
+	}
+	if !strings.Contains(err.Error(), "after 3 bytes") {
+		t.Fatalf("got %q; want the bytes written reported", err)
+	}
+}
+
+func TestFaultReopenFails(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	restore := serial.SetFaultHooks(serial.FaultHooks{
+		Open: func(path string, mode int) (int, error) { return -1, unix.ENOENT },
+	})
+	if err := port1.Reopen(); !errors.Is(err, unix.ENOENT) {
+		restore()
+		t.Fatalf("got %v; want %v", err, unix.ENOENT)
+	}
+	restore()
+
+	if _, err := port1.Write([]byte(testString)); err != serial.ErrPortClosed {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+
+	if err := port1.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFaultReportBreaks(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.ReportBreaks = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	if err := port.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a doubled 0xff, a break split across reads, then a character with a
+	// parity error
+	chunks := [][]byte{{'a', 0xff, 0xff, 0xff}, {0x00}, {0x00, 'b', 0xff, 0x00, 'c'}}
+	defer serial.SetFaultHooks(serial.FaultHooks{
+		Read: func(fd int, b []byte) (int, error) {
+			if len(chunks) == 0 {
+				return 0, unix.EAGAIN
+			}
+			n := copy(b, chunks[0])
+			if chunks[0] = chunks[0][n:]; len(chunks[0]) == 0 {
+				chunks = chunks[1:]
+			}
+			return n, nil
+		},
+	})()
+
+	buf := make([]byte, 4)
+	n, err := port.Read(buf)
+	if err != serial.ErrBreak || string(buf[:n]) != "a\xff" {
+		t.Fatalf("read %q, %v; want %q, %v", buf[:n], err, "a\xff", serial.ErrBreak)
+	}
+
+	n, err = port.Read(buf[:2])
+	if err != nil || string(buf[:n]) != "bc" {
+		t.Fatalf("read %q, %v; want %q, nil", buf[:n], err, "bc")
+	}
+}
+
+func TestFaultTryReadDecodesPending(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.ReportBreaks = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	if err := port.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the break ends the first read, leaving a doubled 0xff and a second
+	// break pending for TryRead to decode
+	chunk := []byte{'a', 0xff, 0x00, 0x00, 'b', 0xff, 0xff, 0xff, 0x00, 0x00, 'c'}
+	defer serial.SetFaultHooks(serial.FaultHooks{
+		Read: func(fd int, b []byte) (int, error) {
+			if len(chunk) == 0 {
+				return 0, unix.EAGAIN
+			}
+			n := copy(b, chunk)
+			chunk = chunk[n:]
+			return n, nil
+		},
+	})()
+
+	buf := make([]byte, 16)
+	n, err := port.Read(buf)
+	if err != serial.ErrBreak || string(buf[:n]) != "a" {
+		t.Fatalf("read %q, %v; want %q, %v", buf[:n], err, "a", serial.ErrBreak)
+	}
+
+	n, err = port.TryRead(buf)
+	if err != serial.ErrBreak || string(buf[:n]) != "b\xff" {
+		t.Fatalf("TryRead %q, %v; want %q, %v", buf[:n], err, "b\xff", serial.ErrBreak)
+	}
+
+	n, err = port.TryRead(buf)
+	if err != nil || string(buf[:n]) != "c" {
+		t.Fatalf("TryRead %q, %v; want %q, nil", buf[:n], err, "c")
+	}
+}
@@ -0,0 +1,61 @@
+//go:build faultinject
+
+package serial
+
+import (
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// FaultHooks replace the system calls ports read and write with, so tests can
+// simulate errors such as device removal without hardware. A nil hook makes
+// the real system call. Only available in builds tagged faultinject.
+type FaultHooks struct {
+	ReadFile  func(handle windows.Handle, b []byte, done *uint32, o *windows.Overlapped) error
+	WriteFile func(handle windows.Handle, b []byte, done *uint32, o *windows.Overlapped) error
+}
+
+var (
+	faultHooks    FaultHooks
+	faultHooksMut sync.RWMutex
+)
+
+// SetFaultHooks installs h for all ports and returns a function that removes
+// it again.
+func SetFaultHooks(h FaultHooks) (restore func()) {
+	faultHooksMut.Lock()
+	defer faultHooksMut.Unlock()
+
+	prev := faultHooks
+	faultHooks = h
+
+	return func() {
+		faultHooksMut.Lock()
+		defer faultHooksMut.Unlock()
+
+		faultHooks = prev
+	}
+}
+
+func sysReadFile(handle windows.Handle, b []byte, done *uint32, o *windows.Overlapped) error {
+	faultHooksMut.RLock()
+	hook := faultHooks.ReadFile
+	faultHooksMut.RUnlock()
+
+	if hook != nil {
+		return hook(handle, b, done, o)
+	}
+	return windows.ReadFile(handle, b, done, o)
+}
+
+func sysWriteFile(handle windows.Handle, b []byte, done *uint32, o *windows.Overlapped) error {
+	faultHooksMut.RLock()
+	hook := faultHooks.WriteFile
+	faultHooksMut.RUnlock()
+
+	if hook != nil {
+		return hook(handle, b, done, o)
+	}
+	return windows.WriteFile(handle, b, done, o)
+}
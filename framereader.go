@@ -0,0 +1,57 @@
+package serial
+
+import "time"
+
+// frameReaderFrames is how many frames a FrameReader buffers at most.
+const frameReaderFrames = 64
+
+// FrameReader reads fixed size frames from a port. Each read takes every byte
+// already received, up to frameReaderFrames frames, so frames arriving in a
+// burst cost one read from the driver rather than one or more each.
+//
+// Bytes after the last complete frame returned stay buffered in the
+// FrameReader, so the port should not be read from by other means while it is
+// in use.
+type FrameReader struct {
+	p    *port
+	size int
+
+	buf        []byte
+	start, end int // buffered bytes not yet returned are buf[start:end]
+}
+
+// FrameReader returns a FrameReader reading frames of size bytes. It panics
+// if size is not positive.
+func (p *port) FrameReader(size int) *FrameReader {
+	if size <= 0 {
+		panic("serial: non-positive frame size")
+	}
+	return &FrameReader{p: p, size: size, buf: make([]byte, size*frameReaderFrames)}
+}
+
+// ReadFrame returns the next frame, reading from the port only if no complete
+// frame is buffered. The frame is valid until the next call to ReadFrame. If
+// the read fails before a frame is complete, the bytes read so far are kept
+// for the next call. Read deadlines and cancellation apply as for Read.
+func (r *FrameReader) ReadFrame() ([]byte, error) {
+	var err error
+
+	if r.end-r.start < r.size {
+		r.end = copy(r.buf, r.buf[r.start:r.end])
+		r.start = 0
+
+		var n int
+		r.p.readMut.Lock()
+		n, err = r.p.tappedRead(r.buf[r.end:], r.size-r.end, time.Time{})
+		r.p.readMut.Unlock()
+
+		r.end += n
+		if r.end < r.size {
+			return nil, err
+		}
+	}
+
+	frame := r.buf[r.start : r.start+r.size]
+	r.start += r.size
+	return frame, err
+}
@@ -0,0 +1,89 @@
+package serial_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/shasderias/serial"
+)
+
+const benchFrameSize = 8
+
+func TestFrameReader(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	const frames = 100
+
+	var sent []byte
+	for i := 0; i < frames*benchFrameSize; i++ {
+		sent = append(sent, byte(i))
+	}
+	go port2.Write(sent)
+
+	if err := port1.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+
+	fr := port1.FrameReader(benchFrameSize)
+	for i := 0; i < frames; i++ {
+		frame, err := fr.ReadFrame()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		want := sent[i*benchFrameSize : (i+1)*benchFrameSize]
+		if !bytes.Equal(frame, want) {
+			t.Fatalf("frame %d: got %x; want %x", i, frame, want)
+		}
+	}
+}
+
+// sendFrames has port2 send n frames to port1, returning port1.
+func sendFrames(b *testing.B, n int) serial.Port {
+	port1, port2 := getTestPorts(b)
+	b.Cleanup(func() {
+		port1.Close()
+		port2.Close()
+	})
+
+	frame := bytes.Repeat([]byte{0x55}, benchFrameSize)
+	go func() {
+		for i := 0; i < n; i++ {
+			if _, err := port2.Write(frame); err != nil {
+				return
+			}
+		}
+	}()
+
+	return port1
+}
+
+func BenchmarkReadFrameNaive(b *testing.B) {
+	port := sendFrames(b, b.N)
+	frame := make([]byte, benchFrameSize)
+
+	b.SetBytes(benchFrameSize)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := port.Read(frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadFrameFrameReader(b *testing.B) {
+	port := sendFrames(b, b.N)
+	fr := port.FrameReader(benchFrameSize)
+
+	b.SetBytes(benchFrameSize)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := fr.ReadFrame(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package serial
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HexDumpTap returns a pair of writers for Config.ReadTap and
+// Config.WriteTap that log traffic to w as an annotated hex dump, one line per
+// 16 bytes:
+//
+//	15:04:05.000000 TX 00000000  68 65 6c 6c 6f 20 77 6f  72 6c 64                 |hello world|
+//
+// Each line carries the time the bytes were tapped, their direction (RX for
+// bytes read, TX for bytes written) and their offset in that direction's
+// traffic. Lines are never interleaved, but as taps copy in the background,
+// RX and TX lines may be logged out of order relative to each other.
+func HexDumpTap(w io.Writer) (rx, tx io.Writer) {
+	d := &hexDumper{w: w}
+	return &hexDumpDir{d: d, name: "RX"}, &hexDumpDir{d: d, name: "TX"}
+}
+
+type hexDumper struct {
+	w   io.Writer
+	mut sync.Mutex
+}
+
+type hexDumpDir struct {
+	d      *hexDumper
+	name   string
+	offset int // guarded by d.mut
+}
+
+func (h *hexDumpDir) Write(b []byte) (int, error) {
+	h.d.mut.Lock()
+	defer h.d.mut.Unlock()
+
+	now := time.Now().Format("15:04:05.000000")
+
+	var sb strings.Builder
+	for i := 0; i < len(b); i += 16 {
+		end := i + 16
+		if end > len(b) {
+			end = len(b)
+		}
+		line := b[i:end]
+
+		fmt.Fprintf(&sb, "%s %s %08x  ", now, h.name, h.offset+i)
+		for j := 0; j < 16; j++ {
+			if j < len(line) {
+				fmt.Fprintf(&sb, "%02x ", line[j])
+			} else {
+				sb.WriteString("   ")
+			}
+			if j == 7 {
+				sb.WriteByte(' ')
+			}
+		}
+
+		sb.WriteString(" |")
+		for _, c := range line {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteString("|\n")
+	}
+	h.offset += len(b)
+
+	if _, err := io.WriteString(h.d.w, sb.String()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,39 @@
+package serial_test
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/shasderias/serial"
+)
+
+func TestHexDumpTap(t *testing.T) {
+	var buf bytes.Buffer
+	rx, tx := serial.HexDumpTap(&buf)
+
+	if _, err := tx.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rx.Write([]byte("0123456789abcdef\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`TX 00000000  68 65 6c 6c 6f 20 77 6f  72 6c 64 {17}\|hello world\|`,
+		`RX 00000000  30 31 32 33 34 35 36 37  38 39 61 62 63 64 65 66  \|0123456789abcdef\|`,
+		`RX 00000010  0d 0a {45}\|\.\.\|`,
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines:\n%s\nwant %d", len(lines), buf.String(), len(want))
+	}
+	for i, line := range lines {
+		re := regexp.MustCompile(`^\d\d:\d\d:\d\d\.\d{6} ` + want[i] + `$`)
+		if !re.MatchString(line) {
+			t.Errorf("line %d: got %q; want match for %q", i, line, re)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package serial
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// serialICounter mirrors struct serial_icounter_struct from linux/serial.h.
+type serialICounter struct {
+	CTS, DSR, RNG, DCD          int32
+	RX, TX                      int32
+	Frame, Overrun, Parity, Brk int32
+	BufOverrun                  int32
+	Reserved                    [9]int32
+}
+
+func ioctl(fd int, req uint, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func getICounter(fd int) (serialICounter, error) {
+	var c serialICounter
+	err := ioctl(fd, unix.TIOCGICOUNT, unsafe.Pointer(&c))
+	return c, err
+}
+
+// serialStruct mirrors struct serial_struct from linux/serial.h.
+type serialStruct struct {
+	Type          int32
+	Line          int32
+	Port          uint32
+	IRQ           int32
+	Flags         int32
+	XmitFIFOSize  int32
+	CustomDivisor int32
+	BaudBase      int32
+	CloseDelay    uint16
+	IOType        int8
+	ReservedChar  [1]int8
+	Hub6          int32
+	ClosingWait   uint16
+	ClosingWait2  uint16
+	IOMemBase     uintptr
+	IOMemRegShift uint16
+	PortHigh      uint32
+	IOMapBase     uintptr
+}
+
+// uartTypes names serial_struct types, from linux/serial_core.h.
+var uartTypes = map[int32]string{
+	0:  "unknown",
+	1:  "8250",
+	2:  "16450",
+	3:  "16550",
+	4:  "16550A",
+	5:  "Cirrus",
+	6:  "16650",
+	7:  "16650V2",
+	8:  "16750",
+	9:  "Startech",
+	10: "16C950",
+	11: "16654",
+	12: "16850",
+	13: "RSA",
+	14: "NS16550A",
+}
+
+// serial_struct flags, from linux/tty_flags.h
+const (
+	asyncSpdCust    = 0x0030
+	asyncSpdMask    = 0x1030
+	asyncLowLatency = 0x2000
+)
+
+func getSerialStruct(fd int) (serialStruct, error) {
+	var ss serialStruct
+	err := ioctl(fd, unix.TIOCGSERIAL, unsafe.Pointer(&ss))
+	return ss, err
+}
+
+func setSerialStruct(fd int, ss *serialStruct) error {
+	return ioctl(fd, unix.TIOCSSERIAL, unsafe.Pointer(ss))
+}
+
+// serialRS485 mirrors struct serial_rs485 from linux/serial.h.
+type serialRS485 struct {
+	Flags              uint32
+	DelayRTSBeforeSend uint32 // in milliseconds
+	DelayRTSAfterSend  uint32 // in milliseconds
+	Padding            [5]uint32
+}
+
+// serial_rs485 flags, from linux/serial.h
+const (
+	serRS485Enabled      = 1 << 0
+	serRS485RTSOnSend    = 1 << 1
+	serRS485RTSAfterSend = 1 << 2
+)
+
+func getRS485(fd int) (serialRS485, error) {
+	var rs serialRS485
+	err := ioctl(fd, unix.TIOCGRS485, unsafe.Pointer(&rs))
+	return rs, err
+}
+
+func setRS485(fd int, rs *serialRS485) error {
+	return ioctl(fd, unix.TIOCSRS485, unsafe.Pointer(rs))
+}
@@ -0,0 +1,110 @@
+package serial
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// keepaliveMaxResponse bounds the bytes read while waiting for a keepalive
+// response, including any noise preceding it.
+const keepaliveMaxResponse = 256
+
+var ErrKeepaliveResponse = errors.New("serial: keepalive response not received")
+
+// Keepalive keeps an idle link warm by sending a ping through a Transactor
+// whenever no transaction has been made for an interval.
+type Keepalive struct {
+	t        *Transactor
+	interval time.Duration
+	ping     []byte
+	response []byte
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	err      error // set before done is closed
+}
+
+// NewKeepalive starts sending ping through t whenever it has been idle for
+// interval. If response is non-nil, each ping must be answered with response
+// within interval; bytes preceding the response are discarded.
+//
+// The Keepalive stops at the first failed ping, see Done and Err, or when
+// Stop is called.
+func NewKeepalive(t *Transactor, interval time.Duration, ping, response []byte) *Keepalive {
+	k := &Keepalive{
+		t:        t,
+		interval: interval,
+		ping:     ping,
+		response: response,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go k.run()
+	return k
+}
+
+// Stop stops sending pings, waiting for a ping in progress to finish.
+func (k *Keepalive) Stop() {
+	k.stopOnce.Do(func() { close(k.stop) })
+	<-k.done
+}
+
+// Done returns a channel that is closed once the Keepalive has stopped.
+func (k *Keepalive) Done() <-chan struct{} {
+	return k.done
+}
+
+// Err returns the error that stopped the Keepalive, or nil if it is running
+// or was stopped by Stop. A missing or garbled response is reported as
+// ErrKeepaliveResponse or os.ErrDeadlineExceeded.
+func (k *Keepalive) Err() error {
+	select {
+	case <-k.done:
+		return k.err
+	default:
+		return nil
+	}
+}
+
+func (k *Keepalive) run() {
+	defer close(k.done)
+
+	timer := time.NewTimer(k.interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-k.stop:
+			return
+		case <-timer.C:
+		}
+
+		if idle := time.Since(k.t.lastActivity()); idle < k.interval {
+			timer.Reset(k.interval - idle)
+			continue
+		}
+
+		if err := k.t.Transact(k.sendPing); err != nil {
+			k.err = err
+			return
+		}
+		timer.Reset(k.interval)
+	}
+}
+
+func (k *Keepalive) sendPing(p Port) error {
+	if _, err := p.Write(k.ping); err != nil {
+		return err
+	}
+	if k.response == nil {
+		return nil
+	}
+
+	_, err := p.ReadUntil(k.response, keepaliveMaxResponse, k.interval)
+	if errors.Is(err, ErrDelimiterNotFound) {
+		return ErrKeepaliveResponse
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package serial_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shasderias/serial"
+)
+
+func TestKeepaliveSendsWhenIdle(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	tr := serial.NewTransactor(port1)
+	k := serial.NewKeepalive(tr, 50*time.Millisecond, []byte("PING"), nil)
+
+	time.Sleep(180 * time.Millisecond)
+	k.Stop()
+
+	if err := k.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+	if err := port2.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	n, _ := port2.Read(buf)
+
+	if pings := strings.Count(string(buf[:n]), "PING"); pings < 2 || pings > 3 {
+		t.Fatalf("got %d pings (%q); want 2 or 3", pings, buf[:n])
+	}
+}
+
+func TestKeepaliveStopsWithoutResponse(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	tr := serial.NewTransactor(port1)
+	k := serial.NewKeepalive(tr, 50*time.Millisecond, []byte("PING"), []byte("PONG"))
+	defer k.Stop()
+
+	select {
+	case <-k.Done():
+	case <-time.After(longSleepDuration):
+		t.Fatal("keepalive still running; want it stopped by the missing response")
+	}
+
+	if err := k.Err(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+}
@@ -0,0 +1,63 @@
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrPortNotFound       = errors.New("serial: no port with serial number")
+	ErrSerialNumberShared = errors.New("serial: serial number shared by several ports")
+)
+
+// PortInfo describes a serial port present on the system.
+type PortInfo struct {
+	// Name is the address to pass to Open, e.g. /dev/ttyUSB0 or COM3.
+	Name string
+
+	// USB identifiers of the adapter behind the port, as lowercase hex for
+	// VID and PID, e.g. "0403" and "6001". Empty for ports not on USB, and
+	// for details the adapter or platform does not report. On Windows,
+	// Product is the driver's description of the device, as Windows does not
+	// keep the USB product string.
+	VID          string
+	PID          string
+	SerialNumber string
+	Product      string
+}
+
+// List returns the serial ports present on the system, in no particular
+// order. On Linux, these are the ttys backed by a device in sysfs; on Windows,
+// those listed under HKLM\HARDWARE\DEVICEMAP\SERIALCOMM.
+func List() ([]PortInfo, error) {
+	return nativeList()
+}
+
+// OpenBySerial opens, as Open does, the port whose USB adapter reports
+// serialNumber, so a port can be found however the system enumerated it. It
+// returns an error wrapping ErrPortNotFound if no port matches, or
+// ErrSerialNumberShared if more than one does, e.g. for the ports of a
+// multi-port adapter.
+func OpenBySerial(serialNumber string, cFns ...func(c *Config)) (Port, error) {
+	ports, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range ports {
+		if info.SerialNumber != "" && info.SerialNumber == serialNumber {
+			names = append(names, info.Name)
+		}
+	}
+
+	switch len(names) {
+	case 0:
+		return nil, fmt.Errorf("%w %q", ErrPortNotFound, serialNumber)
+	case 1:
+		return Open(names[0], cFns...)
+	default:
+		return nil, fmt.Errorf("%w: %q is reported by %s", ErrSerialNumberShared, serialNumber, strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,75 @@
+package serial
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func nativeList() ([]PortInfo, error) {
+	entries, err := os.ReadDir(sysClassTTY)
+	if err != nil {
+		return nil, err
+	}
+
+	var ports []PortInfo
+	for _, e := range entries {
+		name := e.Name()
+		if !hasSerialDevice(name) {
+			continue
+		}
+		info := PortInfo{Name: filepath.Join("/dev", name)}
+		usbInfo(name, &info)
+		ports = append(ports, info)
+	}
+	return ports, nil
+}
+
+// hasSerialDevice reports whether the named tty is backed by a device. Virtual
+// terminals and ptys have none, and the 8250 driver registers placeholder
+// ttyS ports on the platform bus whether or not a UART is present, so those
+// are left out too.
+func hasSerialDevice(name string) bool {
+	dev := filepath.Join(sysClassTTY, name, "device")
+	if _, err := os.Stat(dev); err != nil {
+		return false
+	}
+
+	subsystem, err := os.Readlink(filepath.Join(dev, "subsystem"))
+	if err != nil {
+		return false
+	}
+	return filepath.Base(subsystem) != "platform"
+}
+
+// usbInfo fills in the USB identifiers of the named tty, if it is on USB, from
+// the descriptor attributes of the USB device it hangs off.
+func usbInfo(name string, info *PortInfo) {
+	dev, err := ttyDevice(name)
+	if err != nil {
+		return
+	}
+
+	// the tty's device is a USB interface, or a port below one, so walk up to
+	// the USB device itself
+	for ; strings.HasPrefix(dev, "/sys/devices/"); dev = filepath.Dir(dev) {
+		if _, err := os.Stat(filepath.Join(dev, "idVendor")); err != nil {
+			continue
+		}
+
+		info.VID = sysfsAttr(dev, "idVendor")
+		info.PID = sysfsAttr(dev, "idProduct")
+		info.SerialNumber = sysfsAttr(dev, "serial")
+		info.Product = sysfsAttr(dev, "product")
+		return
+	}
+}
+
+// sysfsAttr returns the value of a sysfs attribute, or "" if it is missing.
+func sysfsAttr(dir, attr string) string {
+	b, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
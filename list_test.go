@@ -0,0 +1,29 @@
+package serial_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shasderias/serial"
+)
+
+func TestList(t *testing.T) {
+	ports, err := serial.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range ports {
+		if p.Name == "" {
+			t.Errorf("port with no name: %+v", p)
+		}
+		t.Logf("%+v", p)
+	}
+}
+
+func TestOpenBySerialNotFound(t *testing.T) {
+	_, err := serial.OpenBySerial("no such serial number")
+	if !errors.Is(err, serial.ErrPortNotFound) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortNotFound)
+	}
+}
@@ -0,0 +1,124 @@
+package serial
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func nativeList() ([]PortInfo, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `HARDWARE\DEVICEMAP\SERIALCOMM`, registry.QUERY_VALUE)
+	if err == registry.ErrNotExist {
+		return nil, nil // created once the first port appears
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	devices, err := k.ReadValueNames(0)
+	if err != nil {
+		return nil, err
+	}
+
+	usb := usbPorts()
+
+	ports := make([]PortInfo, 0, len(devices))
+	for _, device := range devices {
+		name, _, err := k.GetStringValue(device)
+		if err != nil {
+			continue
+		}
+
+		info := usb[name]
+		info.Name = name
+		ports = append(ports, info)
+	}
+	return ports, nil
+}
+
+// usbPorts returns the USB identifiers of the ports of USB devices, keyed by
+// port name, from the device instances under HKLM\SYSTEM\CurrentControlSet\
+// Enum\USB. Instances are keyed by VID_xxxx&PID_xxxx and, for devices that
+// report one, their serial number. Adapters whose drivers enumerate ports
+// under a bus of their own, e.g. FTDIBUS, are not covered.
+func usbPorts() map[string]PortInfo {
+	ports := map[string]PortInfo{}
+
+	enum, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Enum\USB`, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return ports
+	}
+	defer enum.Close()
+
+	ids, err := enum.ReadSubKeyNames(0)
+	if err != nil {
+		return ports
+	}
+
+	for _, id := range ids {
+		vid, pid, ok := parseUSBID(id)
+		if !ok {
+			continue
+		}
+
+		dev, err := registry.OpenKey(enum, id, registry.ENUMERATE_SUB_KEYS)
+		if err != nil {
+			continue
+		}
+		instances, _ := dev.ReadSubKeyNames(0)
+		dev.Close()
+
+		for _, instance := range instances {
+			info := PortInfo{VID: vid, PID: pid}
+			if !strings.Contains(instance, "&") {
+				// generated instance IDs contain '&', serial numbers do not
+				info.SerialNumber = instance
+			}
+
+			name, product, ok := usbInstancePort(enum, id+`\`+instance)
+			if !ok {
+				continue
+			}
+			info.Product = product
+			ports[name] = info
+		}
+	}
+
+	return ports
+}
+
+// usbInstancePort returns the port name and description of a device instance,
+// if it has a port.
+func usbInstancePort(enum registry.Key, path string) (name, product string, ok bool) {
+	params, err := registry.OpenKey(enum, path+`\Device Parameters`, registry.QUERY_VALUE)
+	if err != nil {
+		return "", "", false
+	}
+	name, _, err = params.GetStringValue("PortName")
+	params.Close()
+	if err != nil {
+		return "", "", false
+	}
+
+	if instance, err := registry.OpenKey(enum, path, registry.QUERY_VALUE); err == nil {
+		desc, _, _ := instance.GetStringValue("DeviceDesc")
+		instance.Close()
+
+		// DeviceDesc may be an indirect string, e.g. "@usbser.inf,%desc%;USB
+		// Serial Device", whose text follows the last ';'
+		product = desc[strings.LastIndexByte(desc, ';')+1:]
+	}
+
+	return name, product, true
+}
+
+// parseUSBID parses a USB device ID of the form VID_0403&PID_6001, possibly
+// followed by an interface, e.g. &MI_00.
+func parseUSBID(id string) (vid, pid string, ok bool) {
+	parts := strings.Split(strings.ToUpper(id), "&")
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "VID_") || !strings.HasPrefix(parts[1], "PID_") {
+		return "", "", false
+	}
+	return strings.ToLower(parts[0][4:]), strings.ToLower(parts[1][4:]), true
+}
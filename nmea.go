@@ -0,0 +1,78 @@
+package serial
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// nmeaMaxLength bounds a sentence in bytes, excluding CRLF. NMEA 0183 caps
+// sentences at 82 bytes, but proprietary sentences often run longer.
+const nmeaMaxLength = 256
+
+var ErrNMEAChecksum = errors.New("serial: NMEA checksum missing or mismatched")
+
+// NMEAReader reads NMEA 0183 sentences ($...*CC\r\n) from a Port.
+type NMEAReader struct {
+	r   io.Reader
+	buf []byte // sentence read so far, kept across failed reads
+	b   [1]byte
+}
+
+// NewNMEAReader returns an NMEAReader that reads sentences from r, typically
+// a Port.
+func NewNMEAReader(r io.Reader) *NMEAReader {
+	return &NMEAReader{r: r, buf: make([]byte, 0, nmeaMaxLength)}
+}
+
+// ReadSentence returns the next sentence without the trailing CRLF, e.g.
+// "$GPGLL,4916.45,N,12311.12,W,225444,A*31". Bytes outside a sentence are
+// discarded. If the sentence's checksum is missing or does not match, the
+// sentence is returned along with ErrNMEAChecksum.
+//
+// If reading fails partway through a sentence, e.g. because the read deadline
+// expired, the error is returned and the partial sentence is kept, to be
+// completed by the next call.
+func (n *NMEAReader) ReadSentence() (string, error) {
+	for {
+		c, err := n.r.Read(n.b[:])
+		if c == 1 {
+			switch b := n.b[0]; {
+			case b == '$' || b == '!': // '!' starts encapsulated sentences, e.g. AIS
+				n.buf = append(n.buf[:0], b)
+			case len(n.buf) == 0:
+				// not inside a sentence
+			case b == '\n':
+				sentence := strings.TrimSuffix(string(n.buf), "\r")
+				n.buf = n.buf[:0]
+				return sentence, nmeaVerify(sentence)
+			case len(n.buf) == nmeaMaxLength:
+				n.buf = n.buf[:0] // runaway sentence, resync on the next start delimiter
+			default:
+				n.buf = append(n.buf, b)
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func nmeaVerify(sentence string) error {
+	i := strings.LastIndexByte(sentence, '*')
+	if i < 0 || len(sentence)-i != 3 {
+		return ErrNMEAChecksum
+	}
+
+	want, err := strconv.ParseUint(sentence[i+1:], 16, 8)
+	if err != nil {
+		return ErrNMEAChecksum
+	}
+
+	if ChecksumXOR.Sum([]byte(sentence[1:i])) != uint16(want) {
+		return ErrNMEAChecksum
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package serial_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shasderias/serial"
+)
+
+const ggaSentence = "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"
+
+// stallingReader returns os.ErrDeadlineExceeded once it reaches a stall
+// offset, then carries on from where it stopped.
+type stallingReader struct {
+	data   string
+	stalls []int
+}
+
+func (r *stallingReader) Read(b []byte) (int, error) {
+	if len(r.stalls) > 0 && r.stalls[0] == 0 {
+		r.stalls = r.stalls[1:]
+		return 0, os.ErrDeadlineExceeded
+	}
+	if len(r.data) == 0 {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	n := copy(b, r.data)
+	if len(r.stalls) > 0 && n > r.stalls[0] {
+		n = r.stalls[0]
+	}
+	for i := range r.stalls {
+		r.stalls[i] -= n
+	}
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestNMEAReader(t *testing.T) {
+	r := serial.NewNMEAReader(strings.NewReader("\x00garbage\r\n" + ggaSentence + "\r\n$GPGLL,4916.45,N,12311.12,W,225444,A*30\r\n"))
+
+	got, err := r.ReadSentence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != ggaSentence {
+		t.Fatalf("got %q; want %q", got, ggaSentence)
+	}
+
+	got, err = r.ReadSentence()
+	if !errors.Is(err, serial.ErrNMEAChecksum) {
+		t.Fatalf("got %v; want %v", err, serial.ErrNMEAChecksum)
+	}
+	if !strings.HasPrefix(got, "$GPGLL") {
+		t.Fatalf("got %q; want the mismatched sentence to be returned", got)
+	}
+}
+
+func TestNMEAReaderResumesAfterDeadline(t *testing.T) {
+	r := serial.NewNMEAReader(&stallingReader{data: ggaSentence + "\r\n", stalls: []int{20}})
+
+	if _, err := r.ReadSentence(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	got, err := r.ReadSentence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != ggaSentence {
+		t.Fatalf("got %q; want %q", got, ggaSentence)
+	}
+}
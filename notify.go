@@ -0,0 +1,54 @@
+package serial
+
+import "context"
+
+// Event is something that happened on a port, delivered by Notify.
+type Event int
+
+const (
+	EventNil           Event = iota
+	EventDataAvailable       // bytes were received and are waiting to be read
+	EventLineError           // a parity, framing or overrun error occurred
+	EventBreakReceived       // a break was received
+)
+
+// Notify returns a channel that delivers an event when bytes arrive, a line
+// error occurs or a break is received, for callers that would rather be told
+// of input than block in Read. The events do not consume any input. The
+// channel is closed once ctx is done, the port is closed or waiting fails.
+//
+// On Windows, events are waited for with WaitCommEvent, which shares the
+// port's event mask with WaitModemChange and ModemStatusEvents, so only one
+// of them should wait at a time. On Linux, ttys have no such notification:
+// arrivals are waited for with ppoll, and line errors and breaks are found by
+// polling TIOCGICOUNT, which ptys do not support.
+func (p *port) Notify(ctx context.Context) (<-chan Event, error) {
+	if p.isClosed() {
+		return nil, ErrPortClosed
+	}
+
+	// room for one of each event, as a wait may report all of them
+	ch := make(chan Event, 3)
+
+	go func() {
+		defer close(ch)
+
+		var w eventWatch
+		for {
+			events, err := p.waitEvents(ctx, &w)
+			if err != nil {
+				return
+			}
+
+			for _, ev := range events {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
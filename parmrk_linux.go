@@ -0,0 +1,50 @@
+package serial
+
+// parmrkDecoder undoes the marking of input by PARMRK: a break arrives as
+// 0xff 0x00 0x00, a character c received with a parity or framing error as
+// 0xff 0x00 c, and a literal 0xff is doubled to 0xff 0xff. Mark sequences may
+// be split across reads, so the decoder carries its state between them.
+type parmrkDecoder struct {
+	state   int    // bytes of a mark sequence seen: none, 0xff or 0xff 0x00
+	pending []byte // undecoded input that followed a break
+}
+
+// decode decodes raw in place and returns the number of decoded bytes. If a
+// break is found, decoding stops there and the rest of raw is kept for
+// takePending.
+func (d *parmrkDecoder) decode(raw []byte) (n int, brk bool) {
+	for i, c := range raw {
+		switch d.state {
+		case 0:
+			if c == 0xff {
+				d.state = 1
+				continue
+			}
+		case 1:
+			d.state = 0
+			if c == 0x00 {
+				d.state = 2
+				continue
+			}
+			// 0xff 0xff is a literal 0xff
+		case 2:
+			d.state = 0
+			if c == 0x00 {
+				d.pending = append(append([]byte(nil), raw[i+1:]...), d.pending...)
+				return n, true
+			}
+			// c was received with a parity or framing error, pass it on
+		}
+		raw[n] = c
+		n++
+	}
+	return n, false
+}
+
+// takePending moves as much input kept after a break as fits into b and
+// returns the number of bytes moved.
+func (d *parmrkDecoder) takePending(b []byte) int {
+	n := copy(b, d.pending)
+	d.pending = d.pending[n:]
+	return n
+}
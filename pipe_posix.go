@@ -13,9 +13,12 @@ type pipe struct {
 	rd, wr int
 }
 
+// newPipe returns a pipe whose ends are closed on exec, as the port's fd is,
+// so child processes do not inherit them. Both ends are non-blocking, so
+// signaling a pipe that is already full, or draining one, never blocks.
 func newPipe() (*pipe, error) {
 	fds := make([]int, 2)
-	if err := unix.Pipe(fds); err != nil {
+	if err := unix.Pipe2(fds, unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
 		return nil, err
 	}
 	return &pipe{true, fds[0], fds[1]}, nil
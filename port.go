@@ -0,0 +1,785 @@
+package serial
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Methods reading from the port hold readMut and methods writing to it hold
+// writeMut, so each call's bytes are contiguous. Methods needing both take
+// readMut first.
+
+func (p *port) Read(b []byte) (int, error) {
+	p.readMut.Lock()
+	defer p.readMut.Unlock()
+
+	n, err := p.readConfigured(b, time.Time{})
+	p.stats.countRead(err)
+	return n, err
+}
+
+// WriteString is Write for a string, e.g. a text command, honoring the deadline
+// set by SetWriteDeadline. The string is copied into a pooled buffer rather
+// than converted, so it does not allocate.
+func (p *port) WriteString(s string) (int, error) {
+	bp := GetBuffer(len(s))
+	defer PutBuffer(bp)
+
+	b := *bp
+	copy(b, s)
+	return p.Write(b)
+}
+
+// readConfigured is tappedRead, returning early as ReadMinBytes and
+// ReadInterByteTimeout allow.
+func (p *port) readConfigured(b []byte, deadline time.Time) (int, error) {
+	atLeast, gap := len(b), p.conf.ReadInterByteTimeout
+	if m := p.conf.ReadMinBytes; m > 0 && m < atLeast {
+		atLeast = m
+	}
+	if gap <= 0 {
+		return p.tappedRead(b, atLeast, deadline)
+	}
+
+	var read int
+	for read < atLeast {
+		// before the first byte, only the deadline applies
+		d, idle := deadline, time.Time{}
+		if read > 0 {
+			idle = time.Now().Add(gap)
+			d = earliest(deadline, idle)
+		}
+
+		n, err := p.tappedRead(b[read:], 1, d)
+		read += n
+
+		switch {
+		case err == os.ErrDeadlineExceeded && !idle.IsZero() && !time.Now().Before(idle):
+			// no byte arrived for the inter-byte timeout
+			return read, nil
+		case err != nil:
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+func (p *port) Write(b []byte) (int, error) {
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+
+	n, err := p.tappedWrite(b, time.Time{})
+	p.stats.countWrite(err)
+	return n, err
+}
+
+// copyBufferSize is the size of the buffers ReadFrom and WriteTo copy
+// through, twice io.Copy's default.
+const copyBufferSize = 64 * 1024
+
+// ReadFrom writes what is read from r to the port until r returns io.EOF, so
+// io.Copy to the port copies through a large pooled buffer. Each chunk is
+// written as by Write, honoring the write deadline.
+func (p *port) ReadFrom(r io.Reader) (int64, error) {
+	bp := GetBuffer(copyBufferSize)
+	defer PutBuffer(bp)
+
+	buf := *bp
+	var total int64
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			written, err := p.Write(buf[:n])
+			total += int64(written)
+			if err != nil {
+				return total, err
+			}
+		}
+
+		switch {
+		case rerr == io.EOF:
+			return total, nil
+		case rerr != nil:
+			return total, rerr
+		}
+	}
+}
+
+// WriteTo writes what is read from the port to w, so io.Copy from the port
+// copies through a large pooled buffer. Each read returns as soon as bytes
+// are received, rather than once the buffer is full, and honors the read
+// deadline. The copy ends without error once the port is closed or hung up.
+func (p *port) WriteTo(w io.Writer) (int64, error) {
+	bp := GetBuffer(copyBufferSize)
+	defer PutBuffer(bp)
+
+	buf := *bp
+	var total int64
+	for {
+		p.readMut.Lock()
+		n, rerr := p.tappedRead(buf, 1, time.Time{})
+		p.readMut.Unlock()
+
+		if n > 0 {
+			written, err := w.Write(buf[:n])
+			total += int64(written)
+			if err != nil {
+				return total, err
+			}
+			if written < n {
+				return total, io.ErrShortWrite
+			}
+		}
+
+		switch {
+		case rerr == ErrPortClosed, rerr == io.EOF:
+			return total, nil
+		case rerr != nil:
+			return total, rerr
+		}
+	}
+}
+
+// TryRead reads the bytes already received, up to len(b), returning at once
+// with 0 and a nil error if there are none. Deadlines do not apply.
+func (p *port) TryRead(b []byte) (int, error) {
+	p.readMut.Lock()
+	defer p.readMut.Unlock()
+
+	n, err := p.tryRead(b)
+	p.stats.bytesRead.Add(uint64(n))
+	p.readTap.write(b[:n])
+	return n, err
+}
+
+// ReadByte reads a single byte, honoring the deadline set by
+// SetReadDeadline, so byte-oriented parsers need not wrap the port in a
+// bufio.Reader. It does not allocate.
+//
+// As io.ByteReader requires, an error is only returned if no byte was read,
+// so an ErrOverrun or ErrBreak reported along with the byte is dropped; use
+// Read where those matter.
+func (p *port) ReadByte() (byte, error) {
+	p.readMut.Lock()
+	defer p.readMut.Unlock()
+
+	var b [1]byte
+	if n, err := p.tappedRead(b[:], len(b), time.Time{}); n == 0 {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// ReadContext is Read, returning ctx.Err() with the bytes read so far once ctx
+// is done. The deadline of ctx and the one set by SetReadDeadline both apply,
+// whichever is earlier. A read waiting for input returns as soon as ctx is
+// done.
+func (p *port) ReadContext(ctx context.Context, b []byte) (int, error) {
+	p.readMut.Lock()
+	defer p.readMut.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	p.readCtx = ctx
+	defer func() { p.readCtx = nil }()
+
+	deadline, _ := ctx.Deadline()
+	n, err := p.readConfigured(b, deadline)
+	p.stats.countRead(err)
+	return n, deadlineErr(ctx, err)
+}
+
+// WriteContext is Write, returning ctx.Err() with the number of bytes written
+// so far once ctx is done. The deadline of ctx and the one set by
+// SetWriteDeadline both apply, whichever is earlier. A write waiting for the
+// driver to take more bytes returns as soon as ctx is done.
+func (p *port) WriteContext(ctx context.Context, b []byte) (int, error) {
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	p.writeCtx = ctx
+	defer func() { p.writeCtx = nil }()
+
+	deadline, _ := ctx.Deadline()
+	n, err := p.tappedWrite(b, deadline)
+	p.stats.countWrite(err)
+	return n, deadlineErr(ctx, err)
+}
+
+// WriteRead discards pending input, writes req, then reads until resp is full
+// or timeout elapses, returning the number of bytes read into resp. The port
+// is held throughout, so no other read or write can come between the request
+// and its response. A zero timeout means no timeout.
+func (p *port) WriteRead(req, resp []byte, timeout time.Duration) (int, error) {
+	p.readMut.Lock()
+	defer p.readMut.Unlock()
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	if err := p.flush(true, false); err != nil {
+		return 0, err
+	}
+	if _, err := p.tappedWrite(req, time.Time{}); err != nil {
+		return 0, err
+	}
+	return p.tappedRead(resp, len(resp), deadline)
+}
+
+// tappedRead is read, copying the bytes read to the read tap. Methods reading
+// from the port should use it rather than read.
+func (p *port) tappedRead(b []byte, atLeast int, deadline time.Time) (int, error) {
+	n, err := p.read(b, atLeast, deadline)
+	p.stats.bytesRead.Add(uint64(n))
+	p.readTap.write(b[:n])
+	return n, err
+}
+
+// tappedWrite is write, copying the bytes written to the write tap and local
+// echo. Methods writing to the port should use it rather than write.
+func (p *port) tappedWrite(b []byte, deadline time.Time) (int, error) {
+	n, err := p.write(b, deadline)
+	p.stats.bytesWritten.Add(uint64(n))
+	p.writeTap.write(b[:n])
+	p.localEcho(b[:n])
+	return n, err
+}
+
+// Close closes the port and, if it was opened with OpenNamed, removes it from
+// the registry.
+func (p *port) Close() error {
+	unregister(p)
+	return p.close()
+}
+
+// Flush discards bytes received but not yet read, and bytes written but not
+// yet transmitted, e.g. to resynchronize a protocol after an error.
+func (p *port) Flush() error {
+	return p.flush(true, true)
+}
+
+// FlushInput discards bytes received but not yet read.
+func (p *port) FlushInput() error {
+	return p.flush(true, false)
+}
+
+// FlushOutput discards bytes written but not yet transmitted.
+func (p *port) FlushOutput() error {
+	return p.flush(false, true)
+}
+
+// Drain blocks until every byte written has been transmitted, e.g. before
+// Close, which may otherwise discard bytes still queued in the driver. Writes
+// wait until it returns. Closing the port discards the bytes it is waiting on
+// and makes it return ErrPortClosed.
+func (p *port) Drain() error {
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+
+	return p.drain()
+}
+
+// Reopen closes the port and opens it again with the same address and
+// configuration, e.g. to recover from a disconnect or a wedged USB adapter.
+// Pending reads and writes fail with ErrPortClosed. Deadlines and other state
+// set on the port are kept. The port is opened again even if closing it
+// fails, as the fd or handle is released regardless, which is common for a
+// wedged adapter. If the port cannot be opened again, it remains closed, and
+// methods return ErrPortClosed until a later Reopen succeeds.
+func (p *port) Reopen() error {
+	closeErr := p.close()
+	if err := p.reopen(); err != nil {
+		if closeErr != nil {
+			return fmt.Errorf("error reopening port after close failed with %v: %w", closeErr, err)
+		}
+		return err
+	}
+
+	p.breakMut.Lock()
+	p.breakOn = false
+	p.breakMut.Unlock()
+
+	return nil
+}
+
+// Reconfigure applies cFns to the port's configuration and applies the result
+// to the open port, e.g. to switch baud rate mid-session, without dropping
+// buffered data or touching the modem lines. The configuration is validated
+// as Open validates it, and left unchanged if it is invalid or cannot be
+// applied. InitialLineState and the taps only take effect when the port is
+// opened. Reads and writes in progress are not waited for: they pause while
+// the configuration is applied, at once on Linux and within 30ms on Windows,
+// and continue with the new one.
+func (p *port) Reconfigure(cFns ...func(c *Config)) error {
+	conf, err := p.Config()
+	if err != nil {
+		return err
+	}
+	for _, cFn := range cFns {
+		cFn(&conf)
+	}
+	if err := conf.validate(); err != nil {
+		return err
+	}
+	return p.reconfigure(&conf)
+}
+
+// yield releases p.mut's read lock until a Reconfigure that is waiting for it
+// is done, so reads and writes in progress let it apply rather than holding it
+// off until they return. It must be called with p.mut read locked.
+func (p *port) yield() {
+	if !p.reconfiguring.Load() {
+		return
+	}
+
+	p.mut.RUnlock()
+	p.reconfigMut.Lock()
+	p.reconfigMut.Unlock()
+	p.mut.RLock()
+}
+
+// SupportsBaudRate reports whether rate can be set on the port, e.g. to
+// validate input in a UI. Rates are checked against those the platform
+// supports and, if the port is open, against those its driver reports.
+func (p *port) SupportsBaudRate(rate int) bool {
+	if rate <= 0 {
+		return false
+	}
+	return p.supportsBaudRate(rate)
+}
+
+// SetBreak starts or stops transmitting a continuous break condition, holding
+// the TX line low until stopped.
+func (p *port) SetBreak(on bool) error {
+	p.breakMut.Lock()
+	defer p.breakMut.Unlock()
+
+	if err := p.setBreak(on); err != nil {
+		return err
+	}
+	p.breakOn = on
+	return nil
+}
+
+// defaultBreakDuration is the length of the break SendBreak sends for a zero
+// duration, that of tcsendbreak.
+const defaultBreakDuration = 250 * time.Millisecond
+
+// SendBreak transmits a break condition for d, e.g. to delimit frames, then
+// stops it. A zero d sends a break of the standard 250ms. Writes wait until
+// the break is over.
+func (p *port) SendBreak(d time.Duration) error {
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+	p.breakMut.Lock()
+	defer p.breakMut.Unlock()
+
+	if d <= 0 {
+		d = defaultBreakDuration
+	}
+
+	if err := p.setBreak(true); err != nil {
+		return err
+	}
+	p.breakOn = true
+	time.Sleep(d)
+	if err := p.setBreak(false); err != nil {
+		return err
+	}
+	p.breakOn = false
+	return nil
+}
+
+// Break reports whether a break condition set by SetBreak is being
+// transmitted. Neither Linux nor Windows can query the break state, so it is
+// tracked as SetBreak is called.
+func (p *port) Break() (bool, error) {
+	p.breakMut.Lock()
+	defer p.breakMut.Unlock()
+
+	if p.isClosed() {
+		return false, ErrPortClosed
+	}
+	return p.breakOn, nil
+}
+
+// SetLocalEcho copies every byte subsequently written to the port to w, e.g.
+// so a terminal can show what was typed. Echo happens in software and does not
+// involve the device. Errors writing to w are ignored. A nil w disables echo.
+func (p *port) SetLocalEcho(w io.Writer) {
+	p.echoMut.Lock()
+	defer p.echoMut.Unlock()
+
+	p.echo = w
+}
+
+func (p *port) localEcho(b []byte) {
+	p.echoMut.Lock()
+	defer p.echoMut.Unlock()
+
+	if p.echo == nil || len(b) == 0 {
+		return
+	}
+	p.echo.Write(b)
+}
+
+// ModemStatus returns the current state of the modem status lines, e.g. to
+// check that the other end has asserted DSR before sending. Ptys have no modem
+// lines and fail with ENOTTY.
+func (p *port) ModemStatus() (ModemStatus, error) {
+	return p.modemStatus()
+}
+
+// WaitModemChange waits until CTS, DSR, RI or DCD changes, or ctx is done, and
+// returns the new state of the modem status lines, e.g. to react to a device
+// asserting DCD without polling ModemStatus. Changes are waited for as by
+// ModemStatusEvents. The wait ends within 10ms of ctx being done or the port
+// being closed, and holds nothing open once it has returned.
+func (p *port) WaitModemChange(ctx context.Context) (ModemStatus, error) {
+	if err := p.waitModemChange(ctx); err != nil {
+		return ModemStatus{}, err
+	}
+	return p.modemStatus()
+}
+
+// LineErrors returns the parity, framing, overrun and break errors received
+// since LineErrors was last called or the port was opened, e.g. to
+// resynchronize a protocol after garbled input. On Linux, errors are counted
+// by the driver with TIOCGICOUNT, which ptys do not support. On Windows, they
+// are taken from ClearCommError, which only reports whether each kind of
+// error occurred, so each counts at most once, and clears them for
+// CommStatus and StrictOverrun too.
+func (p *port) LineErrors() (LineErrorCounts, error) {
+	return p.lineErrors()
+}
+
+// ModemStatusEvents returns a channel that delivers the current state of the
+// modem status lines, then a new snapshot every time CTS, DSR, RI or DCD
+// changes. Changes are waited for with WaitCommEvent on Windows. On Linux,
+// where TIOCMIWAIT cannot be interrupted, the lines are polled every 10ms, and
+// their transition counts too where the driver keeps them, so brief pulses are
+// not missed. The channel is closed once ctx is done, the port is closed or
+// waiting fails, and no goroutine is left behind.
+//
+// Rapid changes may be coalesced into one snapshot.
+func (p *port) ModemStatusEvents(ctx context.Context) (<-chan ModemStatus, error) {
+	ms, err := p.modemStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ModemStatus, 1)
+	ch <- ms
+
+	go func() {
+		defer close(ch)
+
+		for {
+			if err := p.waitModemChange(ctx); err != nil {
+				return
+			}
+
+			ms, err := p.modemStatus()
+			if err != nil {
+				return
+			}
+
+			select {
+			case ch <- ms:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// HasInput reports whether there are received bytes waiting to be read,
+// without reading them.
+func (p *port) HasInput() (bool, error) {
+	n, err := p.inputWaiting()
+	return n > 0, err
+}
+
+// InputWaiting returns the number of bytes received but not yet read, without
+// reading them.
+func (p *port) InputWaiting() (int, error) {
+	return p.inputWaiting()
+}
+
+// OutputWaiting returns the number of bytes written but not yet transmitted,
+// e.g. to pace writes under flow control.
+func (p *port) OutputWaiting() (int, error) {
+	return p.outputWaiting()
+}
+
+// ReadUntil reads until the byte sequence delim has been read and returns the
+// bytes read, including delim. If max bytes are read without encountering
+// delim, they are returned with ErrDelimiterNotFound. If timeout, or the
+// deadline set by SetReadDeadline, elapses first, the bytes read so far are
+// returned with os.ErrDeadlineExceeded. A zero timeout means no timeout.
+//
+// Bytes are read one at a time, so nothing past delim is consumed, at the cost
+// of a syscall per byte. For high rate streams, wrap a BufferedPort in a
+// bufio.Reader and use its ReadSlice instead.
+func (p *port) ReadUntil(delim []byte, max int, timeout time.Duration) ([]byte, error) {
+	p.readMut.Lock()
+	defer p.readMut.Unlock()
+
+	if len(delim) == 0 {
+		return nil, errors.New("serial: empty delimiter")
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	var (
+		buf []byte
+		b   [1]byte
+	)
+	for len(buf) < max {
+		n, err := p.tappedRead(b[:], len(b), deadline)
+		buf = append(buf, b[:n]...)
+		if n == 1 && bytes.HasSuffix(buf, delim) {
+			return buf, err
+		}
+		if err != nil {
+			return buf, err
+		}
+	}
+
+	return buf, ErrDelimiterNotFound
+}
+
+// ReadUntilByte is ReadUntil for the common case of a single byte delimiter,
+// e.g. '\n', bounded only by the deadline set by SetReadDeadline.
+func (p *port) ReadUntilByte(delim byte, max int) ([]byte, error) {
+	return p.ReadUntil([]byte{delim}, max, 0)
+}
+
+// ReadFrame reads a frame delimited by idle time, as Modbus RTU frames are:
+// it waits for the first byte, then reads until no byte has arrived for
+// interByteGap, and returns the bytes read. Reading stops early once maxSize
+// bytes are read. If timeout, or the deadline set by SetReadDeadline, elapses
+// first, the bytes read so far are returned with os.ErrDeadlineExceeded. A
+// zero timeout means no timeout. maxSize must be positive.
+//
+// The gap is measured with the read deadline granularity of the platform, so
+// it should be at least a few milliseconds.
+func (p *port) ReadFrame(maxSize int, interByteGap, timeout time.Duration) ([]byte, error) {
+	p.readMut.Lock()
+	defer p.readMut.Unlock()
+
+	if maxSize <= 0 {
+		return nil, errors.New("serial: non-positive frame size")
+	}
+
+	var limit time.Time
+	if timeout > 0 {
+		limit = time.Now().Add(timeout)
+	}
+
+	frame := make([]byte, maxSize)
+	var read int
+
+	for read < maxSize {
+		// before the first byte, only the timeout applies
+		deadline, gap := limit, false
+		if read > 0 {
+			deadline = time.Now().Add(interByteGap)
+			if limit.IsZero() || deadline.Before(limit) {
+				gap = true
+			} else {
+				deadline = limit
+			}
+		}
+
+		n, err := p.tappedRead(frame[read:], 1, deadline)
+		read += n
+
+		switch {
+		case err == os.ErrDeadlineExceeded && gap && time.Now().After(deadline):
+			// the line has been idle for interByteGap, the frame is complete
+			return frame[:read], nil
+		case err != nil:
+			return frame[:read], err
+		}
+	}
+
+	return frame[:read], nil
+}
+
+// DrainUntilIdle discards input until no byte has arrived for idle. If the
+// line has not gone idle by the time max has elapsed, it gives up and returns
+// os.ErrDeadlineExceeded.
+func (p *port) DrainUntilIdle(idle, max time.Duration) error {
+	p.readMut.Lock()
+	defer p.readMut.Unlock()
+
+	limit := time.Now().Add(max)
+
+	bp := GetBuffer(64)
+	defer PutBuffer(bp)
+	buf := *bp
+
+	for {
+		deadline, capped := time.Now().Add(idle), false
+		if deadline.After(limit) {
+			deadline, capped = limit, true
+		}
+
+		n, err := p.tappedRead(buf, len(buf), deadline)
+		switch {
+		case err == os.ErrDeadlineExceeded && time.Now().Before(deadline):
+			// the deadline set by SetReadDeadline fired, not ours
+			return err
+		case err == os.ErrDeadlineExceeded && n == 0:
+			if capped {
+				return os.ErrDeadlineExceeded
+			}
+			return nil
+		case err != nil && err != os.ErrDeadlineExceeded:
+			return err
+		}
+
+		if !time.Now().Before(limit) {
+			return os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// SetReadCancel registers cancel, e.g. a context's Done channel, as a way to
+// abort reads: once cancel is closed, pending reads return the bytes read so
+// far with ErrReadCanceled, as do later reads until another channel is
+// registered. Reads notice at once, except that on Windows a read already
+// waiting only notices a newly registered channel within a polling interval. A
+// nil cancel clears the registration.
+func (p *port) SetReadCancel(cancel <-chan struct{}) {
+	p.readCancelMut.Lock()
+	p.readCancel = cancel
+	p.readCancelMut.Unlock()
+
+	p.wakeRead()
+}
+
+func (p *port) readCancelChan() <-chan struct{} {
+	p.readCancelMut.Lock()
+	defer p.readCancelMut.Unlock()
+
+	return p.readCancel
+}
+
+func (p *port) readCanceled() bool {
+	p.readCancelMut.Lock()
+	defer p.readCancelMut.Unlock()
+
+	select {
+	case <-p.readCancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// watch calls wake once done is closed, until the returned stop function is
+// called. stop waits for the watching goroutine to exit, so wake is not called
+// once it has returned. A nil done is never closed, so nothing is watched.
+func watch(done <-chan struct{}, wake func()) (stop func()) {
+	if done == nil {
+		return func() {}
+	}
+
+	stopc := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-done:
+			wake()
+		case <-stopc:
+		}
+	}()
+
+	return func() {
+		close(stopc)
+		<-exited
+	}
+}
+
+// ctxDone returns ctx.Done(), or nil if ctx is nil.
+func ctxDone(ctx context.Context) <-chan struct{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Done()
+}
+
+// ctxErr returns ctx.Err(), or nil if ctx is nil.
+func ctxErr(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// deadlineErr reports an I/O error caused by ctx as ctx.Err(), so a deadline
+// reached through ctx reads as context.DeadlineExceeded. The deadline may be
+// reached before ctx notices.
+func deadlineErr(ctx context.Context, err error) error {
+	if err != os.ErrDeadlineExceeded {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// writeFailed wraps err, from a write the system failed after written bytes
+// of it were sent, so callers resuming a transmission can tell where it
+// stopped.
+func writeFailed(written int, err error) error {
+	return fmt.Errorf("serial: write failed after %d bytes: %w", written, err)
+}
+
+// orDefault returns c, or def if c is zero.
+func orDefault(c, def byte) byte {
+	if c == 0 {
+		return def
+	}
+	return c
+}
+
+// earliest returns the earlier of two deadlines, where a zero deadline is no
+// deadline.
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+func deadlineExpired(t time.Time) bool {
+	return !t.IsZero() && time.Now().After(t)
+}
@@ -0,0 +1,65 @@
+package serial
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrNameInUse = errors.New("serial: port name in use")
+
+// named holds ports opened with OpenNamed by name. A nil entry reserves a
+// name while its port is being opened.
+var (
+	named    = map[string]*port{}
+	namedMut sync.Mutex
+)
+
+// OpenNamed opens the port at address as Open does, and registers it under
+// name, so it can be retrieved with Get. The port is removed from the registry
+// when it is closed. If name is already registered, OpenNamed returns
+// ErrNameInUse without opening the port.
+func OpenNamed(name, address string, cFns ...func(c *Config)) (Port, error) {
+	namedMut.Lock()
+	if _, ok := named[name]; ok {
+		namedMut.Unlock()
+		return nil, ErrNameInUse
+	}
+	named[name] = nil
+	namedMut.Unlock()
+
+	p, err := Open(address, cFns...)
+
+	namedMut.Lock()
+	defer namedMut.Unlock()
+
+	if err != nil {
+		delete(named, name)
+		return nil, err
+	}
+	named[name] = p.(*port)
+	return p, nil
+}
+
+// Get returns the port registered under name by OpenNamed, if it is open.
+func Get(name string) (Port, bool) {
+	namedMut.Lock()
+	defer namedMut.Unlock()
+
+	p := named[name]
+	if p == nil {
+		return nil, false
+	}
+	return p, true
+}
+
+// unregister removes p from the registry, if it is registered.
+func unregister(p *port) {
+	namedMut.Lock()
+	defer namedMut.Unlock()
+
+	for name, rp := range named {
+		if rp == p {
+			delete(named, name)
+		}
+	}
+}
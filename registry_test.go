@@ -0,0 +1,35 @@
+package serial_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shasderias/serial"
+)
+
+func TestRegistry(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	opt := func(c *serial.Config) { c.BaudRate = baudRate }
+
+	port, err := serial.OpenNamed("modem", portPath, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := serial.Get("modem"); !ok || got != port {
+		t.Fatalf("got %v, %v; want the opened port", got, ok)
+	}
+
+	if _, err := serial.OpenNamed("modem", portPath, opt); !errors.Is(err, serial.ErrNameInUse) {
+		t.Fatalf("got %v; want %v", err, serial.ErrNameInUse)
+	}
+
+	if err := port.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := serial.Get("modem"); ok {
+		t.Fatal("closed port still registered")
+	}
+}
@@ -1,11 +1,28 @@
 package serial
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"time"
 )
 
+// Common baud rates. Config.BaudRate also accepts rates not listed here.
+const (
+	Baud1200   = 1200
+	Baud2400   = 2400
+	Baud4800   = 4800
+	Baud9600   = 9600
+	Baud19200  = 19200
+	Baud38400  = 38400
+	Baud57600  = 57600
+	Baud115200 = 115200
+	Baud230400 = 230400
+	Baud460800 = 460800
+	Baud921600 = 921600
+)
+
 type Parity int
 
 const (
@@ -23,28 +40,547 @@ const (
 	StopBits2
 )
 
+// The characters that resume and halt transmission under software flow
+// control, unless configured otherwise.
+const (
+	DefaultXonChar  = 0x11 // DC1
+	DefaultXoffChar = 0x13 // DC3
+)
+
+// FlowControl is the flow control mode of a port.
+type FlowControl int
+
+const (
+	FlowControlNil     FlowControl = iota
+	FlowControlNone                // no flow control
+	FlowControlRTSCTS              // hardware flow control on RTS and CTS
+	FlowControlXONXOFF             // software flow control with XON and XOFF characters
+)
+
+// ModemStatus is the state of the modem status lines, as driven by the
+// other end.
+type ModemStatus struct {
+	CTS bool // clear to send
+	DSR bool // data set ready
+	RI  bool // ring indicator
+	DCD bool // data carrier detect
+}
+
+// RS485 configures the driver to switch an RS-485 transceiver between
+// transmitting and receiving by toggling RTS around each transmission.
+type RS485 struct {
+	Enabled      bool
+	RTSOnSend    bool // RTS level while sending, asserted if true
+	RTSAfterSend bool // RTS level after sending, asserted if true
+
+	// DelayBeforeSend and DelayAfterSend are how long RTS is held before
+	// and after sending, with millisecond resolution.
+	DelayBeforeSend time.Duration
+	DelayAfterSend  time.Duration
+}
+
+// LineErrorCounts counts the receive errors on a line, so a protocol can
+// tell that it needs to resynchronize.
+type LineErrorCounts struct {
+	Parity  int // characters received with a parity error
+	Framing int // characters received with a framing error
+	Overrun int // characters lost to a UART or input buffer overrun
+	Break   int // break conditions received
+}
+
+// LineState is the state DTR and RTS are put in when a port is opened.
+type LineState int
+
+const (
+	LineStateNil          LineState = iota
+	LineStateLeave                  // leave DTR and RTS untouched
+	LineStateAssertBoth             // assert DTR and RTS
+	LineStateDeassertBoth           // deassert DTR and RTS
+)
+
+// LineLevel is the level InitialDTR or InitialRTS puts a line at when a port
+// is opened.
+type LineLevel int
+
+const (
+	LineLevelNil  LineLevel = iota // as InitialLineState puts it
+	LineLevelLow                   // deasserted
+	LineLevelHigh                  // asserted
+)
+
+// ApplyMode controls when the settings applied as a port is opened take
+// effect. Linux only, Windows applies settings immediately.
+type ApplyMode int
+
+const (
+	ApplyModeNil   ApplyMode = iota
+	ApplyModeNow             // take effect immediately (TCSANOW)
+	ApplyModeDrain           // take effect once pending output is sent (TCSADRAIN)
+	ApplyModeFlush           // as ApplyModeDrain, also discarding unread input (TCSAFLUSH)
+)
+
 var (
 	ErrPortInUse  = errors.New("serial: port in use")
 	ErrPortClosed = errors.New("serial: port closed")
+	ErrOverrun    = errors.New("serial: input overrun, data lost")
+
+	ErrUnsupportedBaudRate = errors.New("serial: unsupported baud rate")
+
+	ErrReadCanceled = errors.New("serial: read canceled")
+
+	// ErrPortDisconnected is returned once the device behind an open port has
+	// been removed, e.g. a USB adapter was unplugged. It is wrapped along with
+	// the OS error that reported the removal, so test for it with errors.Is.
+	ErrPortDisconnected = errors.New("serial: port disconnected")
+
+	// ErrUnsupported is returned by operations the platform or driver cannot
+	// perform.
+	ErrUnsupported = errors.New("serial: operation not supported")
+
+	ErrDelimiterNotFound = errors.New("serial: delimiter not found")
+
+	// ErrBreak is returned by Read on a port opened with ReportBreaks when a
+	// break is received.
+	ErrBreak = errors.New("serial: break received")
+
+	// ErrCommAborted is returned by Read and Write on a port opened with
+	// AbortOnError once a communications error has halted I/O.
+	ErrCommAborted = errors.New("serial: I/O aborted on communications error")
 )
 
+// inUseError is ErrPortInUse, wrapping the OS error that reported it.
+type inUseError struct {
+	err error
+}
+
+func (e *inUseError) Error() string        { return ErrPortInUse.Error() + ": " + e.err.Error() }
+func (e *inUseError) Is(target error) bool { return target == ErrPortInUse }
+func (e *inUseError) Unwrap() error        { return e.err }
+
+// disconnectedError is ErrPortDisconnected, wrapping the OS error that
+// reported it.
+type disconnectedError struct {
+	err error
+}
+
+func (e *disconnectedError) Error() string {
+	return ErrPortDisconnected.Error() + ": " + e.err.Error()
+}
+func (e *disconnectedError) Is(target error) bool { return target == ErrPortDisconnected }
+func (e *disconnectedError) Unwrap() error        { return e.err }
+
 type Config struct {
 	BaudRate int      // default 19200
 	DataBits int      // default 8
 	StopBits StopBits // default StopBits1
 	Parity   Parity   // default ParityEven
+
+	// FlowControl is the flow control mode, default FlowControlNone. With
+	// FlowControlRTSCTS, the driver drives RTS, overriding InitialLineState
+	// for it.
+	FlowControl FlowControl
+
+	// XonChar and XoffChar are the characters that resume and halt
+	// transmission under FlowControlXONXOFF, default DC1 (0x11) and DC3
+	// (0x13).
+	XonChar  byte
+	XoffChar byte
+
+	// HonorModemControl makes the driver act on the modem status lines rather
+	// than ignore them, e.g. for a real modem. On Linux, it clears CLOCAL, so
+	// the tty is hung up once DCD drops and Read returns io.EOF. Open does not
+	// wait for carrier, as the port is opened non-blocking, but a blocking
+	// open of the same tty elsewhere would. On Windows, which has no DCD
+	// sensitivity, it sets fDsrSensitivity instead, so the driver discards
+	// bytes received while DSR is deasserted. The default ignores the lines,
+	// as a three-wire link has none.
+	HonorModemControl bool
+
+	// DisableParityCheck stops received characters being checked for parity,
+	// while parity is still generated on transmit, for devices that send bad
+	// parity but require it on what they receive.
+	DisableParityCheck bool
+
+	// BaudRateFallback lists rates to try, in order, if BaudRate is not
+	// supported by the platform or driver. The rate applied is reported by
+	// Port.Config.
+	BaudRateFallback []int
+
+	// Exclusive stops other processes opening the port while it is open, so
+	// their I/O cannot interleave with ours; they fail with ErrPortInUse. On
+	// Linux, it sets TIOCEXCL, which root can override. Windows always opens
+	// ports exclusively.
+	Exclusive bool
+
+	// DisableHangupOnClose keeps DTR/RTS asserted when the port is closed,
+	// e.g. to avoid resetting an Arduino on exit. Linux only, clears HUPCL.
+	DisableHangupOnClose bool
+
+	// StrictOverrun makes Read return ErrOverrun, along with the data read so
+	// far, once the driver reports that input was lost to a buffer overrun.
+	StrictOverrun bool
+
+	// AddressBit enables address bit (9-bit) mode for multidrop buses: the
+	// 9th bit of each character marks it as an address, and the driver can
+	// ignore data not meant for this node. Parity is not used in this mode.
+	// Linux 6.0+ with a supporting UART only; Open returns ErrUnsupported
+	// otherwise.
+	AddressBit bool
+
+	// ReportBreaks makes a received break return ErrBreak from Read, along
+	// with the data read before it, rather than arrive as a 0x00 byte
+	// indistinguishable from data. Linux only, using PARMRK; Open returns
+	// ErrUnsupported elsewhere.
+	ReportBreaks bool
+
+	// AbortOnError halts reads and writes at the first parity, framing or
+	// overrun error, so no data received after it is consumed unnoticed. Read
+	// and Write then return ErrCommAborted until the error is cleared with
+	// ClearErrors or CommStatus. Windows only; Linux has no equivalent and Open returns
+	// ErrUnsupported, StrictOverrun being the closest behavior there.
+	AbortOnError bool
+
+	// XonLimit and XoffLimit are the flow control watermarks of the input
+	// buffer: input is resumed once no more than XonLimit bytes are buffered,
+	// and halted once fewer than XoffLimit bytes of space remain. Halting
+	// early favors latency, halting late throughput. Windows only, zero keeps
+	// the driver's default.
+	XonLimit  int
+	XoffLimit int
+
+	// InputBufferSize and OutputBufferSize request the sizes, in bytes, of
+	// the driver's input and output buffers, e.g. a larger input buffer for
+	// high-throughput capture. Drivers may round or ignore them. Windows only;
+	// Linux ignores them, its tty buffers being sized by the kernel. Zero
+	// keeps the driver's current size.
+	InputBufferSize  int
+	OutputBufferSize int
+
+	// RxFIFOTrigger is the number of bytes the UART buffers in its receive
+	// FIFO before interrupting, the closest Linux analog of a watermark.
+	// Drivers round it to a level the UART supports. Linux only, on UARTs
+	// exposing rx_trig_bytes in sysfs (8250 family); zero keeps the current
+	// level.
+	RxFIFOTrigger int
+
+	// LowLatency sets ASYNC_LOW_LATENCY on the port, which makes FTDI and
+	// similar USB adapters drop their 16ms latency timer to the minimum, for
+	// quick request/response round trips. Drivers without serial_struct
+	// support ignore it, noting so to Logf. Linux only.
+	LowLatency bool
+
+	// RS485, if enabled, has the driver toggle RTS around each transmission
+	// for a half-duplex RS-485 bus. Linux only, on drivers supporting
+	// TIOCSRS485; Open fails if the driver rejects it. The zero value leaves
+	// the driver's RS-485 mode untouched.
+	RS485 RS485
+
+	// ReadMinBytes and ReadInterByteTimeout let Read return before b is
+	// full, as VMIN and VTIME do for blocking ttys: Read returns once
+	// ReadMinBytes bytes are read, or once at least one byte is read and no
+	// more arrive for ReadInterByteTimeout. The deadline set by
+	// SetReadDeadline still applies, returning os.ErrDeadlineExceeded with
+	// the bytes read so far. Zero keeps the default, reading until b is full.
+	// On Linux, they are also applied as VMIN and VTIME, so that with
+	// ReadMinBytes alone poll waits for that many bytes, but as the port is
+	// non-blocking, Read implements them itself.
+	ReadMinBytes         int
+	ReadInterByteTimeout time.Duration
+
+	// InitialLineState is applied to DTR and RTS as the port is opened, before
+	// any I/O. By default, Linux leaves the lines untouched and Windows
+	// deasserts them.
+	InitialLineState LineState
+
+	// InitialDTR and InitialRTS put DTR and RTS at a level as the port is
+	// opened, overriding InitialLineState for that line, e.g. to keep DTR low
+	// so an attached Arduino is not reset. Both lines are set at once: with
+	// a single TIOCMSET on Linux, and in the DCB applied with the other
+	// settings on Windows. On Linux, the tty driver asserts the lines as the
+	// port is opened, before they can be set, so a line set low may pulse
+	// high briefly.
+	InitialDTR LineLevel
+	InitialRTS LineLevel
+
+	// ApplyMode controls when settings take effect as the port is opened.
+	// Linux only, default ApplyModeNow.
+	ApplyMode ApplyMode
+
+	// Backoff, if set, returns the Backoff pacing a Write that is waiting
+	// for buffer space; it is called once per call. Reads wait in poll
+	// instead. Linux only, default a fixed 1ms.
+	Backoff func() Backoff
+
+	// RestoreOnClose has no effect.
+	//
+	// Deprecated: the settings the port had before it was opened are
+	// restored when it is closed unless KeepSettingsOnClose is set.
+	RestoreOnClose bool
+
+	// KeepSettingsOnClose leaves the port with the settings this program
+	// applied when it is closed, e.g. raw mode. By default the settings it
+	// had before it was opened are restored, so it is not left configured
+	// for this program.
+	KeepSettingsOnClose bool
+
+	// ReadTap and WriteTap, if set, receive a copy of every byte read from and
+	// written to the port, e.g. for protocol debugging. Copies are made in the
+	// background; if a tap falls more than 64 KiB behind, bytes are dropped
+	// rather than holding up the port.
+	ReadTap  io.Writer
+	WriteTap io.Writer
+
+	// Logf, if set, receives notes about settings that could not be applied
+	// but were not treated as errors.
+	Logf func(format string, v ...any)
 }
 
+// validate rejects settings that no platform supports, before any attempt
+// is made to open the port.
+func (c *Config) validate() error {
+	if c.BaudRate < 0 {
+		return fmt.Errorf("%w: %d", ErrUnsupportedBaudRate, c.BaudRate)
+	}
+	if c.DataBits != 0 && (c.DataBits < 5 || c.DataBits > 8) {
+		return fmt.Errorf("serial: unsupported data bits: %d, want 5 to 8", c.DataBits)
+	}
+	if c.StopBits < StopBitsNil || c.StopBits > StopBits2 {
+		return fmt.Errorf("serial: unsupported stop bits: %d", c.StopBits)
+	}
+	if c.Parity < ParityNil || c.Parity > ParityEven {
+		return fmt.Errorf("serial: unsupported parity: %d", c.Parity)
+	}
+	if c.XonLimit < 0 || c.XonLimit > 0xffff || c.XoffLimit < 0 || c.XoffLimit > 0xffff {
+		return fmt.Errorf("serial: flow control limits out of range: %d, %d", c.XonLimit, c.XoffLimit)
+	}
+	if xon := orDefault(c.XonChar, DefaultXonChar); xon == orDefault(c.XoffChar, DefaultXoffChar) {
+		return fmt.Errorf("serial: XON and XOFF characters are both %#x", xon)
+	}
+	if c.RS485.DelayBeforeSend < 0 || c.RS485.DelayAfterSend < 0 {
+		return fmt.Errorf("serial: negative RS-485 delay: %v, %v", c.RS485.DelayBeforeSend, c.RS485.DelayAfterSend)
+	}
+	if c.ReadMinBytes < 0 || c.ReadInterByteTimeout < 0 {
+		return fmt.Errorf("serial: negative read minimum or timeout: %d, %v", c.ReadMinBytes, c.ReadInterByteTimeout)
+	}
+	if c.InitialLineState < LineStateNil || c.InitialLineState > LineStateDeassertBoth {
+		return fmt.Errorf("serial: unsupported line state: %d", c.InitialLineState)
+	}
+	if c.InitialDTR < LineLevelNil || c.InitialDTR > LineLevelHigh || c.InitialRTS < LineLevelNil || c.InitialRTS > LineLevelHigh {
+		return fmt.Errorf("serial: unsupported line levels: %d, %d", c.InitialDTR, c.InitialRTS)
+	}
+	if c.InputBufferSize < 0 || c.OutputBufferSize < 0 {
+		return fmt.Errorf("serial: negative buffer size: %d, %d", c.InputBufferSize, c.OutputBufferSize)
+	}
+	if c.RxFIFOTrigger < 0 {
+		return fmt.Errorf("serial: negative receive FIFO trigger: %d", c.RxFIFOTrigger)
+	}
+	for _, rate := range c.BaudRateFallback {
+		if rate <= 0 {
+			return fmt.Errorf("%w: fallback %d", ErrUnsupportedBaudRate, rate)
+		}
+	}
+	return c.validateNative()
+}
+
+// initialLines returns the levels InitialLineState, InitialDTR and InitialRTS
+// put DTR and RTS at, LineLevelNil leaving a line untouched. def is the level
+// of lines InitialLineState leaves to the platform's default.
+func (c *Config) initialLines(def LineLevel) (dtr, rts LineLevel) {
+	both := def
+	switch c.InitialLineState {
+	case LineStateLeave:
+		both = LineLevelNil
+	case LineStateAssertBoth:
+		both = LineLevelHigh
+	case LineStateDeassertBoth:
+		both = LineLevelLow
+	}
+
+	dtr, rts = both, both
+	if c.InitialDTR != LineLevelNil {
+		dtr = c.InitialDTR
+	}
+	if c.InitialRTS != LineLevelNil {
+		rts = c.InitialRTS
+	}
+	return dtr, rts
+}
+
+func (c *Config) logf(format string, v ...any) {
+	if c.Logf != nil {
+		c.Logf(format, v...)
+	}
+}
+
+// Port is an open serial port.
+//
+// On Linux, Read returns io.EOF once the line is hung up, e.g. because the
+// carrier dropped on a port opened with HonorModemControl, so io.Copy and
+// scanners stop cleanly.
 type Port interface {
 	io.ReadWriteCloser
 	SetReadDeadline(t time.Time) error
 	SetWriteDeadline(t time.Time) error
+
+	// ReadFrom writes what is read from r to the port, for io.Copy.
+	ReadFrom(r io.Reader) (int64, error)
+
+	// WriteTo writes what is read from the port to w, for io.Copy, until the
+	// port is closed.
+	WriteTo(w io.Writer) (int64, error)
+
+	// WriteString is Write for a string.
+	WriteString(s string) (int, error)
+
+	// TryRead reads the bytes already received, without waiting for more.
+	TryRead(b []byte) (int, error)
+
+	// ReadByte reads a single byte.
+	ReadByte() (byte, error)
+
+	// ReadContext is Read, aborted with ctx.Err() once ctx is done.
+	ReadContext(ctx context.Context, b []byte) (int, error)
+
+	// WriteContext is Write, aborted with ctx.Err() once ctx is done.
+	WriteContext(ctx context.Context, b []byte) (int, error)
+
+	// SetReadCancel registers a channel that, once closed, makes pending and
+	// future reads return ErrReadCanceled. A nil channel clears it.
+	SetReadCancel(cancel <-chan struct{})
+
+	// ReadFrame reads a frame that ends once no byte has arrived for
+	// interByteGap, reading at most maxSize bytes and giving up once timeout
+	// has elapsed.
+	ReadFrame(maxSize int, interByteGap, timeout time.Duration) ([]byte, error)
+
+	// DrainUntilIdle discards input until no byte has arrived for idle, or
+	// returns os.ErrDeadlineExceeded if the line is still busy after max.
+	DrainUntilIdle(idle, max time.Duration) error
+
+	// Flush discards both unread input and untransmitted output.
+	Flush() error
+
+	// FlushInput discards bytes received but not yet read.
+	FlushInput() error
+
+	// FlushOutput discards bytes written but not yet transmitted.
+	FlushOutput() error
+
+	// Drain blocks until every byte written has been transmitted.
+	Drain() error
+
+	// SetLocalEcho copies bytes subsequently written to the port to w. A nil
+	// w disables echo.
+	SetLocalEcho(w io.Writer)
+
+	// Reopen closes the port and opens it again with the same address and
+	// configuration, keeping deadlines set on the port.
+	Reopen() error
+
+	// SupportsBaudRate reports whether rate can be set on the port.
+	SupportsBaudRate(rate int) bool
+
+	// SetDTR asserts or deasserts DTR until changed again or the port is
+	// closed.
+	SetDTR(on bool) error
+
+	// SetRTS asserts or deasserts RTS until changed again or the port is
+	// closed.
+	SetRTS(on bool) error
+
+	// SetBreak starts or stops transmitting a continuous break condition.
+	SetBreak(on bool) error
+
+	// SendBreak transmits a break condition for d, 250ms if d is zero.
+	SendBreak(d time.Duration) error
+
+	// Break reports whether a break condition set by SetBreak is being
+	// transmitted.
+	Break() (bool, error)
+
+	// ResumeTransmission clears a stuck XOFF condition, resuming output and
+	// sending XON.
+	ResumeTransmission() error
+
+	// XoffHold reports whether output is held because XOFF was received.
+	XoffHold() (bool, error)
+
+	// UARTType returns the type of UART behind the port, e.g. "16550A".
+	UARTType() (string, error)
+
+	// WriteRead discards pending input, writes req and reads the response into
+	// resp, without letting other reads or writes interleave.
+	WriteRead(req, resp []byte, timeout time.Duration) (int, error)
+
+	// FlowControl reads back the flow control mode in effect on the port.
+	FlowControl() (FlowControl, error)
+
+	// Config returns the configuration the port was opened with, as applied,
+	// e.g. with BaudRate set to the fallback rate used.
+	Config() (Config, error)
+
+	// Reconfigure changes the configuration of the open port, without
+	// reopening it.
+	Reconfigure(cFns ...func(c *Config)) error
+
+	// CurrentConfig returns the port's configuration with the line settings
+	// read back from the driver.
+	CurrentConfig() (Config, error)
+
+	// ModemStatus returns the current state of the modem status lines.
+	ModemStatus() (ModemStatus, error)
+
+	// WaitModemChange waits for a modem status line to change, and returns
+	// the new state of the lines.
+	WaitModemChange(ctx context.Context) (ModemStatus, error)
+
+	// Stats returns counters of the port's I/O.
+	Stats() Stats
+
+	// LineErrors returns the receive errors since it was last called.
+	LineErrors() (LineErrorCounts, error)
+
+	// ModemStatusEvents delivers the modem status lines, then a new snapshot
+	// every time they change, until ctx is done.
+	ModemStatusEvents(ctx context.Context) (<-chan ModemStatus, error)
+
+	// Notify delivers an event when bytes arrive, a line error occurs or a
+	// break is received, until ctx is done.
+	Notify(ctx context.Context) (<-chan Event, error)
+
+	// HasInput reports whether there are bytes waiting to be read.
+	HasInput() (bool, error)
+
+	// InputWaiting returns the number of bytes waiting to be read.
+	InputWaiting() (int, error)
+
+	// OutputWaiting returns the number of bytes waiting to be transmitted.
+	OutputWaiting() (int, error)
+
+	// FrameReader returns a FrameReader for frames of size bytes.
+	FrameReader(size int) *FrameReader
+
+	// ReadUntil reads until delim, reading at most max bytes and giving up
+	// once timeout has elapsed.
+	ReadUntil(delim []byte, max int, timeout time.Duration) ([]byte, error)
+
+	// ReadUntilByte reads until the byte delim, reading at most max bytes.
+	ReadUntilByte(delim byte, max int) ([]byte, error)
 }
 
+// Open opens the port at address, e.g. /dev/ttyUSB0 on Linux or COM3 on
+// Windows, configured by cFns.
+//
+// Drivers for multiport cards expose each channel as a port of its own, e.g.
+// /dev/ttyS4 to /dev/ttyS7 or COM5 to COM8, so a channel is selected by its
+// address. There is no generic channel selection call to make at open.
 func Open(address string, cFns ...func(c *Config)) (p Port, err error) {
 	conf := Config{}
 	for _, cFn := range cFns {
 		cFn(&conf)
 	}
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
 	return nativeOpen(address, &conf)
 }
@@ -47,3 +47,7 @@ var charSizes = map[int]uint32{
 	7: unix.CS7,
 	8: unix.CS8,
 }
+
+// addrb enables address bit (9-bit) mode, from linux/termbits.h. Added in
+// Linux 6.0 and not yet defined by x/sys/unix.
+const addrb = 0x20000000
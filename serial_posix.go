@@ -3,9 +3,16 @@
 package serial
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sys/unix"
@@ -16,14 +23,58 @@ const (
 )
 
 type port struct {
+	path string
+	conf Config
+
 	fd int
 
+	origTermios unix.Termios // settings before open, restored on close
+
 	mut         sync.RWMutex
 	closeSignal *pipe
 
+	// readWake and writeWake interrupt a read waiting in waitReadable and a
+	// write waiting in waitWritable, so they recheck their deadline,
+	// cancellation and context. They are signaled without mut, so wakeMut
+	// keeps them from being closed under a signal.
+	readWake, writeWake *pipe
+	wakeMut             sync.Mutex
+
 	closing    bool
+	draining   bool // a Drain is waiting on a copy of fd
 	closingMut sync.Mutex
 
+	readMut, writeMut sync.Mutex
+
+	// reconfiguring is set while reconfigure, holding reconfigMut, waits for
+	// and holds mut, see yield
+	reconfiguring atomic.Bool
+	reconfigMut   sync.Mutex
+
+	echo    io.Writer
+	echoMut sync.Mutex
+
+	readCancel    <-chan struct{}
+	readCancelMut sync.Mutex
+
+	// contexts of ReadContext and WriteContext, guarded by readMut and writeMut
+	readCtx, writeCtx context.Context
+
+	readTap, writeTap *tap
+
+	stats stats
+
+	breakOn  bool
+	breakMut sync.Mutex
+
+	parmrk parmrkDecoder // if ReportBreaks, guarded by readMut
+
+	overruns    int32 // overrun count when last checked, if StrictOverrun
+	overrunsMut sync.Mutex
+
+	lineErrs    serialICounter // error counts when LineErrors was last called
+	lineErrsMut sync.Mutex
+
 	readDeadline     time.Time
 	readDeadlineMut  sync.Mutex
 	writeDeadline    time.Time
@@ -31,124 +82,583 @@ type port struct {
 }
 
 func nativeOpen(path string, conf *Config) (*port, error) {
-	fd, err := unix.Open(
-		path,
+	p := &port{
+		path: path, conf: *conf,
+		readTap: newTap(conf.ReadTap), writeTap: newTap(conf.WriteTap),
+	}
+
+	if err := p.open(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// validateNative rejects settings Linux does not support.
+func (c *Config) validateNative() error {
+	if c.AbortOnError {
+		return fmt.Errorf("abort on error: %w", ErrUnsupported)
+	}
+	return nil
+}
+
+// open opens p.path and configures it according to p.conf.
+func (p *port) open() (err error) {
+	conf := &p.conf
+
+	fd, err := sysOpen(
+		p.path,
 		// https://www.cmrr.umn.edu/~strupp/serial.html#2_5_2
 		// https://www.gnu.org/software/libc/manual/html_node/Operating-Modes.html
 		// O_NOCTTY: no controlling terminal - prevents input from affecting this process
 		// O_NDELAY: don't wait for DCD signal line to be on space voltage
 		// O_CLOEXEC: close fd on exec, child processes don't need access to the serial port
 		unix.O_RDWR|unix.O_NOCTTY|unix.O_NDELAY|unix.O_CLOEXEC,
-		0,
 	)
 	if err != nil {
-		return nil, err
+		if err == unix.EBUSY {
+			// another process holds the port with TIOCEXCL. EACCES is left
+			// alone, it means the port's permissions deny us.
+			return &inUseError{err}
+		}
+		return err
+	}
+	defer func() {
+		if err != nil {
+			unix.Close(fd)
+		}
+	}()
+
+	if conf.Exclusive {
+		if err := ioctl(fd, unix.TIOCEXCL, nil); err != nil {
+			return fmt.Errorf("error locking port: %w", err)
+		}
 	}
 
 	// O_NDELAY/O_NONBLOCK has overloaded semantics, setting it on Open() means don't block for
 	// a "long time" when opening. For serial ports, it may mean waiting for a carrier signal.
 	// After the port is opened, the flag determines whether IO is blocking or non-blocking.
-	// As we use blocking I/O with timeouts (VTIME/VMIN), we need to clear O_NDELAY/O_NONBLOCK.
-	// TODO: what about writes?
-	flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0)
+	// The port is deliberately kept non-blocking: reads wait for input in poll, where Close
+	// can wake them, and writes retry on EAGAIN, so neither can block past a deadline or
+	// Close. VTIME/VMIN have no effect on a non-blocking fd.
+	flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFL, 0)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	flags &^= unix.O_NDELAY
-
-	_, err = unix.FcntlInt(uintptr(fd), unix.F_SETFD, flags)
+	_, err = unix.FcntlInt(uintptr(fd), unix.F_SETFL, flags|unix.O_NONBLOCK)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	tty, err := unix.IoctlGetTermios(fd, unix.TCGETS)
 	if err != nil {
-		return nil, fmt.Errorf("error getting termios: %w", err)
+		return fmt.Errorf("error getting termios: %w", err)
+	}
+
+	origTermios := *tty
+
+	if err := p.configure(fd, tty); err != nil {
+		return err
+	}
+
+	dtr, rts := conf.initialLines(LineLevelNil)
+	if err := setLineLevels(fd, dtr, rts); err != nil {
+		return err
+	}
+
+	// the pipes are made last, so no error path needs to close them
+	closeSignal, err := newPipe()
+	if err != nil {
+		return err
+	}
+	readWake, err := newPipe()
+	if err != nil {
+		closeSignal.Close()
+		return err
+	}
+	writeWake, err := newPipe()
+	if err != nil {
+		closeSignal.Close()
+		readWake.Close()
+		return err
 	}
 
+	p.fd = fd
+	p.closeSignal = closeSignal
+	p.wakeMut.Lock()
+	p.readWake, p.writeWake = readWake, writeWake
+	p.wakeMut.Unlock()
+	p.origTermios = origTermios
+	p.lineErrs, _ = sysGetICounter(fd) // best effort, ptys do not count errors
+
+	return nil
+}
+
+// configure applies p.conf to fd, starting from its current settings in tty.
+// The modem lines are left alone.
+func (p *port) configure(fd int, tty *unix.Termios) error {
+	conf := &p.conf
+
 	termiosSetRaw(tty)
+	if conf.HonorModemControl {
+		tty.Cflag &^= unix.CLOCAL // hang up once DCD drops
+	}
+	p.parmrk = parmrkDecoder{}
 
-	if err := termiosSetBaudrate(tty, conf.BaudRate); err != nil {
-		return nil, err
+	if err := p.setBaudRate(fd, tty); err != nil {
+		return err
 	}
 	if err := termiosSetCharSize(tty, conf.DataBits); err != nil {
-		return nil, err
+		return err
 	}
 	if err := termiosSetParity(tty, conf.Parity); err != nil {
-		return nil, err
+		return err
+	}
+	if conf.DisableParityCheck {
+		tty.Iflag &^= unix.INPCK // generate parity, but don't check it
+	}
+	if err := termiosSetFlowControl(tty, conf.FlowControl, conf.XonChar, conf.XoffChar); err != nil {
+		return err
+	}
+	if conf.ReportBreaks {
+		tty.Iflag |= unix.PARMRK // mark breaks and errors, see parmrkDecoder
 	}
 	if err := termiosSetStopBits(tty, conf.StopBits); err != nil {
-		return nil, err
+		return err
+	}
+	if conf.AddressBit {
+		termiosSetAddressBit(tty)
+	} else {
+		tty.Cflag &^= addrb
 	}
 
-	termiosSetTimeout(tty, tickResolution, 0)
+	termiosSetHangupOnClose(tty, !conf.DisableHangupOnClose)
+	termiosSetTimeout(tty, vtime(conf.ReadInterByteTimeout, conf.ReadMinBytes), vmin(conf.ReadMinBytes))
 
-	err = unix.IoctlSetTermios(fd, unix.TCSETS, tty)
+	req, err := termiosSetRequest(conf.ApplyMode, tty.Cflag&unix.CBAUD == unix.BOTHER)
 	if err != nil {
-		return nil, fmt.Errorf("error setting termios: %w", err)
+		return err
 	}
 
-	closeSignal, err := newPipe()
+	err = unix.IoctlSetTermios(fd, req, tty)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("error setting termios: %w", err)
 	}
 
-	return &port{fd: fd, closeSignal: closeSignal}, nil
+	if conf.AddressBit {
+		// drivers without address bit support clear ADDRB rather than fail
+		tty, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+		if err != nil {
+			return fmt.Errorf("error getting termios: %w", err)
+		}
+		if tty.Cflag&addrb == 0 {
+			return fmt.Errorf("address bit mode: %w", ErrUnsupported)
+		}
+	}
+
+	if conf.RxFIFOTrigger > 0 {
+		dev, err := fdPath(fd)
+		if err != nil {
+			return err
+		}
+		if err := setRxTrigger(filepath.Base(dev), conf.RxFIFOTrigger); err != nil {
+			return err
+		}
+	}
+
+	if conf.RS485.Enabled {
+		if err := setRS485(fd, rs485(conf.RS485)); err != nil {
+			return fmt.Errorf("error enabling RS-485 mode: %w", err)
+		}
+	}
+
+	if conf.LowLatency {
+		if err := setLowLatency(fd); err != nil {
+			conf.logf("serial: %s does not support low latency mode: %v", p.path, err)
+		}
+	}
+
+	if conf.StrictOverrun {
+		c, err := sysGetICounter(fd)
+		if err != nil {
+			return fmt.Errorf("error getting overrun count: %w", err)
+		}
+		p.overruns = c.Overrun + c.BufOverrun
+	}
+
+	return nil
+}
+
+// reconfigure applies conf to the open port, keeping the previous
+// configuration, in the driver as well as in p.conf, if it cannot be applied.
+func (p *port) reconfigure(conf *Config) error {
+	p.reconfigMut.Lock()
+	defer p.reconfigMut.Unlock()
+	p.reconfiguring.Store(true)
+	defer p.reconfiguring.Store(false)
+
+	// so pending reads and writes yield
+	p.wakeRead()
+	p.wakeWrite()
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return ErrPortClosed
+	}
+
+	saved, err := getDriverSettings(p.fd)
+	if err != nil {
+		return err
+	}
+
+	prev := p.conf
+	p.conf = *conf
+	tty := saved.tty
+	if err := p.configure(p.fd, &tty); err != nil {
+		// configure may have failed after applying some settings
+		saved.restore(p.fd)
+		p.conf = prev
+		return err
+	}
+	return nil
+}
+
+// driverSettings holds the driver state configure changes, so it can be put
+// back if configure fails part way.
+type driverSettings struct {
+	tty      unix.Termios
+	termios2 bool          // tty was read with TCGETS2 and carries its speed
+	ss       *serialStruct // nil if the driver has none
+	rs485    *serialRS485  // nil if the driver does not support RS-485
+}
+
+func getDriverSettings(fd int) (driverSettings, error) {
+	var s driverSettings
+
+	req := uint(unix.TCGETS)
+	if hasTermios2(fd) {
+		req, s.termios2 = unix.TCGETS2, true
+	}
+	tty, err := unix.IoctlGetTermios(fd, req)
+	if err != nil {
+		return s, fmt.Errorf("error getting termios: %w", err)
+	}
+	s.tty = *tty
+
+	if ss, err := getSerialStruct(fd); err == nil {
+		s.ss = &ss
+	}
+	if rs, err := getRS485(fd); err == nil {
+		s.rs485 = &rs
+	}
+	return s, nil
+}
+
+// restore applies s to fd, on a best effort basis.
+func (s *driverSettings) restore(fd int) {
+	req := uint(unix.TCSETS)
+	if s.termios2 {
+		req = unix.TCSETS2
+	}
+	tty := s.tty
+	unix.IoctlSetTermios(fd, req, &tty)
+
+	if s.ss != nil {
+		ss := *s.ss
+		setSerialStruct(fd, &ss)
+	}
+	if s.rs485 != nil {
+		rs := *s.rs485
+		setRS485(fd, &rs)
+	}
 }
 
-func (p *port) Read(b []byte) (int, error) {
+// read is Read, returning once at least atLeast bytes are read rather than
+// once b is full, with an additional deadline, ignored if zero, that is
+// honored alongside the deadline set by SetReadDeadline.
+func (p *port) read(b []byte, atLeast int, deadline time.Time) (int, error) {
 	p.mut.RLock()
 	defer p.mut.RUnlock()
 
 	var read int
 
 	for {
+		p.yield()
+
 		if p.isClosing() || p.fd == -1 {
 			return read, ErrPortClosed
 		}
-		if p.readDeadlineExpired() {
+		if p.readDeadlineExpired() || deadlineExpired(deadline) {
 			return read, os.ErrDeadlineExceeded
 		}
+		if p.readCanceled() {
+			return read, ErrReadCanceled
+		}
+		if err := ctxErr(p.readCtx); err != nil {
+			return read, err
+		}
+		if err := p.checkOverrun(); err != nil {
+			return read, err
+		}
+
+		var (
+			n   int
+			err error
+		)
+		if len(p.parmrk.pending) > 0 {
+			n = p.parmrk.takePending(b[read:])
+		} else {
+			n, err = sysRead(p.fd, b[read:])
+		}
 
-		n, err := unix.Read(p.fd, b[read:])
 		switch {
+		case err == unix.EIO, err == nil && n == 0 && read < len(b):
+			// the tty was hung up, e.g. the carrier dropped
+			return read, p.hangupErr(err)
 		case err == unix.EAGAIN:
-			time.Sleep(time.Millisecond)
+			if err := p.waitReadable(earliest(p.readDeadlineTime(), deadline)); err != nil {
+				return read, err
+			}
 		case err != nil:
 			return n + read, err
 		default:
+			var brk bool
+			if p.conf.ReportBreaks {
+				n, brk = p.parmrk.decode(b[read : read+n])
+			}
 			read += n
+
+			if brk {
+				return read, ErrBreak
+			}
+		}
+
+		if read >= atLeast {
+			return read, p.checkOverrun()
+		}
+	}
+}
+
+// tryRead is read, reading only bytes already received and returning at once
+// if there are none.
+func (p *port) tryRead(b []byte) (int, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return 0, ErrPortClosed
+	}
+
+	var n int
+	if len(p.parmrk.pending) > 0 {
+		n = p.parmrk.takePending(b)
+	} else {
+		var err error
+		n, err = sysRead(p.fd, b)
+		switch {
+		case err == unix.EAGAIN:
+			return 0, nil
+		case err == unix.EIO, err == nil && n == 0 && len(b) > 0:
+			return 0, p.hangupErr(err)
+		case err != nil:
+			return 0, err
+		}
+	}
+
+	if p.conf.ReportBreaks {
+		var brk bool
+		if n, brk = p.parmrk.decode(b[:n]); brk {
+			return n, ErrBreak
+		}
+	}
+	return n, p.checkOverrun()
+}
+
+// maxReadWait bounds how long Notify and the modem line polling of
+// WaitModemChange and ModemStatusEvents wait in poll before rechecking their
+// state, as ttys cannot wake them when that state changes.
+const maxReadWait = 10 * time.Millisecond
+
+// waitReadable waits until the port has input or deadline, ignored if zero,
+// passes. It also returns once wakeRead is called, e.g. because the read
+// deadline changed, or the read's cancel channel or context is done, so the
+// caller can recheck them. If Close signals while it waits, it returns
+// ErrPortClosed. The wait is timed by ppoll to the nanosecond, so short
+// deadlines are not rounded up.
+func (p *port) waitReadable(deadline time.Time) error {
+	defer watch(p.readCancelChan(), p.wakeRead)()
+	defer watch(ctxDone(p.readCtx), p.wakeRead)()
+
+	var ts *unix.Timespec
+	if !deadline.IsZero() {
+		wait := time.Until(deadline)
+		if wait < 0 {
+			wait = 0
+		}
+		t := unix.NsecToTimespec(int64(wait))
+		ts = &t
+	}
+
+	fds := []unix.PollFd{
+		{Fd: int32(p.fd), Events: unix.POLLIN},
+		{Fd: int32(p.closeSignal.ReadFD()), Events: unix.POLLIN},
+		{Fd: int32(p.readWake.ReadFD()), Events: unix.POLLIN},
+	}
+	if _, err := unix.Ppoll(fds, ts, nil); err != nil && err != unix.EINTR {
+		return err
+	}
+	if fds[1].Revents != 0 {
+		return ErrPortClosed
+	}
+	if fds[2].Revents != 0 {
+		drainPipe(p.readWake)
+	}
+	return nil
+}
+
+// wakeRead makes a read waiting in waitReadable return to recheck its state.
+func (p *port) wakeRead() {
+	p.wakeMut.Lock()
+	defer p.wakeMut.Unlock()
+
+	if p.readWake != nil {
+		p.readWake.Write([]byte{0})
+	}
+}
+
+// waitWritable waits for wait, or until deadline, ignored if zero, before a
+// write the driver had no room for is retried. It returns early once wakeWrite
+// is called, e.g. because the write deadline changed or the write's context is
+// done. If Close signals while it waits, it returns ErrPortClosed.
+func (p *port) waitWritable(wait time.Duration, deadline time.Time) error {
+	defer watch(ctxDone(p.writeCtx), p.wakeWrite)()
+
+	if !deadline.IsZero() {
+		if d := time.Until(deadline); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	ts := unix.NsecToTimespec(int64(wait))
+
+	fds := []unix.PollFd{
+		{Fd: int32(p.closeSignal.ReadFD()), Events: unix.POLLIN},
+		{Fd: int32(p.writeWake.ReadFD()), Events: unix.POLLIN},
+	}
+	if _, err := unix.Ppoll(fds, &ts, nil); err != nil && err != unix.EINTR {
+		return err
+	}
+	if fds[0].Revents != 0 {
+		return ErrPortClosed
+	}
+	if fds[1].Revents != 0 {
+		drainPipe(p.writeWake)
+	}
+	return nil
+}
+
+// wakeWrite makes a write waiting in waitWritable return to recheck its state.
+func (p *port) wakeWrite() {
+	p.wakeMut.Lock()
+	defer p.wakeMut.Unlock()
+
+	if p.writeWake != nil {
+		p.writeWake.Write([]byte{0})
+	}
+}
+
+// drainPipe discards the signals written to pp.
+func drainPipe(pp *pipe) {
+	var buf [64]byte
+	for {
+		if n, err := pp.Read(buf[:]); err != nil || n < len(buf) {
+			return
 		}
+	}
+}
+
+func (p *port) backoff() Backoff {
+	if p.conf.Backoff == nil {
+		return defaultBackoff
+	}
+	return p.conf.Backoff()
+}
 
-		if read == len(b) {
-			return read, nil
+// hangupErr is the error for a read that found the tty hung up, failing with
+// err, nil if it read end of file: ErrPortDisconnected, wrapping err, if the
+// device is gone, e.g. a USB adapter was unplugged, otherwise io.EOF, as when
+// the remote end dropped the carrier.
+func (p *port) hangupErr(err error) error {
+	if _, statErr := os.Stat(p.path); errors.Is(statErr, fs.ErrNotExist) {
+		if err == nil {
+			return ErrPortDisconnected
 		}
+		return &disconnectedError{err}
+	}
+	return io.EOF
+}
+
+// checkOverrun returns ErrOverrun if input has been lost to an overrun since
+// it was last called. It only checks if the port is configured with
+// StrictOverrun.
+func (p *port) checkOverrun() error {
+	if !p.conf.StrictOverrun {
+		return nil
+	}
+
+	p.overrunsMut.Lock()
+	defer p.overrunsMut.Unlock()
+
+	c, err := sysGetICounter(p.fd)
+	if err != nil {
+		return err
+	}
+
+	overruns := c.Overrun + c.BufOverrun
+	if overruns == p.overruns {
+		return nil
 	}
+	p.overruns = overruns
+	return ErrOverrun
 }
 
-func (p *port) Write(b []byte) (int, error) {
+// write is Write with an additional deadline, ignored if zero, that is honored
+// alongside the deadline set by SetWriteDeadline.
+func (p *port) write(b []byte, deadline time.Time) (int, error) {
 	p.mut.RLock()
 	defer p.mut.RUnlock()
 
 	var written int
+	backoff := p.backoff()
 
 	for {
+		p.yield()
+
 		if p.isClosing() || p.fd == -1 {
 			return written, ErrPortClosed
 		}
-		if p.writeDeadlineExpired() {
+		if p.writeDeadlineExpired() || deadlineExpired(deadline) {
 			return written, os.ErrDeadlineExceeded
 		}
+		if err := ctxErr(p.writeCtx); err != nil {
+			return written, err
+		}
 
-		n, err := unix.Write(p.fd, b[written:])
+		n, err := sysWrite(p.fd, b[written:])
 		switch {
 		case err == unix.EAGAIN:
-			time.Sleep(time.Millisecond)
+			if err := p.waitWritable(backoff.Next(), earliest(p.writeDeadlineTime(), deadline)); err != nil {
+				return written, err
+			}
 		case err != nil:
-			return n + written, err
+			return written, writeFailed(written, err)
 		default:
 			written += n
+			backoff.Reset()
 		}
 
 		if written == len(b) {
@@ -157,30 +667,58 @@ func (p *port) Write(b []byte) (int, error) {
 	}
 }
 
+// SetReadDeadline sets the read deadline, waking a pending read so it waits
+// for the new deadline instead.
 func (p *port) SetReadDeadline(t time.Time) error {
 	p.readDeadlineMut.Lock()
-	defer p.readDeadlineMut.Unlock()
 	p.readDeadline = t
+	p.readDeadlineMut.Unlock()
+
+	p.wakeRead()
 	return nil
 }
 
+func (p *port) readDeadlineTime() time.Time {
+	p.readDeadlineMut.Lock()
+	defer p.readDeadlineMut.Unlock()
+
+	return p.readDeadline
+}
+
 func (p *port) readDeadlineExpired() bool {
 	p.readDeadlineMut.Lock()
 	defer p.readDeadlineMut.Unlock()
-	return !p.readDeadline.IsZero() && time.Now().After(p.readDeadline)
+	return deadlineExpired(p.readDeadline)
 }
 
+// SetWriteDeadline sets the write deadline, waking a pending write so it
+// honors the new deadline.
 func (p *port) SetWriteDeadline(t time.Time) error {
 	p.writeDeadlineMut.Lock()
-	defer p.writeDeadlineMut.Unlock()
 	p.writeDeadline = t
+	p.writeDeadlineMut.Unlock()
+
+	p.wakeWrite()
 	return nil
 }
 
+func (p *port) writeDeadlineTime() time.Time {
+	p.writeDeadlineMut.Lock()
+	defer p.writeDeadlineMut.Unlock()
+
+	return p.writeDeadline
+}
+
 func (p *port) writeDeadlineExpired() bool {
 	p.writeDeadlineMut.Lock()
 	defer p.writeDeadlineMut.Unlock()
-	return !p.writeDeadline.IsZero() && time.Now().After(p.writeDeadline)
+	return deadlineExpired(p.writeDeadline)
+}
+
+func (p *port) isClosed() bool {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	return p.isClosing() || p.fd == -1
 }
 
 func (p *port) isClosing() bool {
@@ -189,73 +727,884 @@ func (p *port) isClosing() bool {
 	return p.closing
 }
 
-func (p *port) Close() error {
+func (p *port) close() error {
 	if p.fd == -1 {
 		return nil
 	}
 
 	p.closingMut.Lock()
 	p.closing = true
+	draining := p.draining
 	p.closingMut.Unlock()
 
-	//p.closeSignal.Write([]byte{0})
+	// wake reads waiting for input, so they see the port closing and release
+	// the lock
+	p.closeSignal.Write([]byte{0})
+	if draining {
+		// end a pending Drain by discarding what it waits to transmit
+		unix.IoctlSetInt(p.fd, unix.TCFLSH, unix.TCOFLUSH)
+	}
 
 	p.mut.Lock()
 	defer p.mut.Unlock()
 
+	if !p.conf.KeepSettingsOnClose {
+		tty := p.origTermios
+		if p.conf.DisableHangupOnClose {
+			// the original settings would hang up as the fd closes
+			tty.Cflag &^= unix.HUPCL
+		}
+		// best effort, the port is closed regardless
+		unix.IoctlSetTermios(p.fd, unix.TCSETS, &tty)
+	}
+
 	err := unix.Close(p.fd)
-	//p.closeSignal.Close()
+	p.closeSignal.Close()
+
+	p.wakeMut.Lock()
+	p.readWake.Close()
+	p.writeWake.Close()
+	p.readWake, p.writeWake = nil, nil
+	p.wakeMut.Unlock()
 
 	p.fd = -1
 
 	return err
 }
 
-func termiosSetRaw(tty *unix.Termios) {
-	tty.Cflag |= unix.CREAD  // enable receiver
-	tty.Cflag |= unix.CLOCAL // ignore modem control lines
-
-	tty.Oflag &^= unix.OPOST // disable output processing
+// Termios returns the port's current terminal settings, for flags this
+// package does not model. Modify them and apply them with SetTermios. This is
+// an advanced, Linux-only escape hatch; assert a Port to
+// interface{ Termios() (*unix.Termios, error); SetTermios(*unix.Termios) error }
+// to use it.
+func (p *port) Termios() (*unix.Termios, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
 
-	tty.Lflag &^= unix.ECHO   // don't echo input
-	tty.Lflag &^= unix.ISIG   // don't interpret INTR, SUSP, DSUSP and QUIT characters
-	tty.Lflag &^= unix.ICANON // disable canonical mode
-	tty.Lflag &^= unix.IEXTEN // disable implementation-defined input processing
-	// disable echo handling, shouldn't be necessary as ECHO bit is off but just in case
-	tty.Lflag &^= unix.ECHOE
-	tty.Lflag &^= unix.ECHOK
-	tty.Lflag &^= unix.ECHONL
-	tty.Lflag &^= unix.ECHOCTL
-	tty.Lflag &^= unix.ECHOPRT
-	tty.Lflag &^= unix.ECHOKE
+	if p.isClosing() || p.fd == -1 {
+		return nil, ErrPortClosed
+	}
+	return unix.IoctlGetTermios(p.fd, unix.TCGETS)
+}
 
-	// disable software flow control
-	tty.Iflag &^= unix.IXON | unix.IXOFF | unix.IXANY
+// SetTermios applies tty to the port immediately. Settings made through it
+// are not reflected in the port's Config, and are lost on Reopen. Changing
+// VMIN or VTIME breaks deadline handling.
+func (p *port) SetTermios(tty *unix.Termios) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
 
-	// disable special handling of received bytes
-	tty.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL
+	if p.isClosing() || p.fd == -1 {
+		return ErrPortClosed
+	}
+	return unix.IoctlSetTermios(p.fd, unix.TCSETS, tty)
 }
 
-func termiosSetBaudrate(tty *unix.Termios, baudRate int) error {
-	b, ok := baudRates[baudRate]
-	if !ok {
-		return fmt.Errorf("unsupported baud rate: %d", baudRate)
+// ResumeTransmission recovers from a lost XON under software flow control by
+// resuming output as if XON had been received, and sending XON in case the
+// other end is waiting on one too.
+func (p *port) ResumeTransmission() error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return ErrPortClosed
 	}
-	tty.Cflag &^= unix.CBAUD
-	tty.Cflag |= b
-	return nil
-}
 
-func termiosSetCharSize(tty *unix.Termios, charSize int) error {
-	s, ok := charSizes[charSize]
-	if !ok {
-		return fmt.Errorf("unsupported character size: %d", charSize)
+	if err := unix.IoctlSetInt(p.fd, unix.TCXONC, unix.TCOON); err != nil {
+		return err
 	}
-	tty.Cflag &^= unix.CSIZE
+	return unix.IoctlSetInt(p.fd, unix.TCXONC, unix.TCION)
+}
+
+// XoffHold returns ErrUnsupported, Linux does not report whether output is
+// held by XOFF.
+func (p *port) XoffHold() (bool, error) {
+	return false, ErrUnsupported
+}
+
+// UARTType returns the type of UART the driver reports, e.g. "8250" (no FIFO)
+// or "16550A". USB adapters emulate a UART and may report any type; drivers
+// without serial_struct support return an error.
+func (p *port) UARTType() (string, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return "", ErrPortClosed
+	}
+
+	ss, err := getSerialStruct(p.fd)
+	if err != nil {
+		return "", err
+	}
+
+	if name, ok := uartTypes[ss.Type]; ok {
+		return name, nil
+	}
+	return fmt.Sprintf("unknown (%d)", ss.Type), nil
+}
+
+// DevicePath returns the canonical path of the device the port has open, e.g.
+// /dev/ttyUSB0 when it was opened through a /dev/serial/by-id symlink. Linux
+// only; assert a Port to interface{ DevicePath() (string, error) } to use it.
+func (p *port) DevicePath() (string, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return "", ErrPortClosed
+	}
+	return fdPath(p.fd)
+}
+
+// SysfsPath returns the sysfs node of the hardware behind the port, e.g. the
+// USB interface of an adapter, for logging which physical device is in use or
+// reaching its sysfs attributes. Virtual ttys such as ptys have none. Linux
+// only, like DevicePath.
+func (p *port) SysfsPath() (string, error) {
+	dev, err := p.DevicePath()
+	if err != nil {
+		return "", err
+	}
+	return ttyDevice(filepath.Base(dev))
+}
+
+// FlowControl reads back the flow control mode in effect from termios. If
+// both hardware and software flow control are enabled, FlowControlRTSCTS is
+// reported.
+func (p *port) FlowControl() (FlowControl, error) {
+	tty, err := p.Termios()
+	if err != nil {
+		return FlowControlNil, err
+	}
+	return termiosFlowControl(tty), nil
+}
+
+func termiosFlowControl(tty *unix.Termios) FlowControl {
+	switch {
+	case tty.Cflag&unix.CRTSCTS != 0:
+		return FlowControlRTSCTS
+	case tty.Iflag&(unix.IXON|unix.IXOFF) != 0:
+		return FlowControlXONXOFF
+	default:
+		return FlowControlNone
+	}
+}
+
+// CurrentConfig returns the port's configuration with the baud rate,
+// character size, parity, stop bits and flow control read back from the
+// driver, e.g. to confirm that defaults took effect. Ptys clear PARENB, so
+// they report no parity. The remaining settings are as configured.
+func (p *port) CurrentConfig() (Config, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return Config{}, ErrPortClosed
+	}
+
+	// TCGETS2 also reports the speed of BOTHER rates
+	tty, err := unix.IoctlGetTermios(p.fd, unix.TCGETS2)
+	if err != nil {
+		if tty, err = unix.IoctlGetTermios(p.fd, unix.TCGETS); err != nil {
+			return Config{}, fmt.Errorf("error getting termios: %w", err)
+		}
+	}
+
+	conf := p.conf
+
+	if rate, ok := p.termiosBaudRate(tty); ok {
+		conf.BaudRate = rate
+	}
+	for size, cs := range charSizes {
+		if size != 0 && tty.Cflag&unix.CSIZE == cs {
+			conf.DataBits = size
+		}
+	}
+	switch {
+	case tty.Cflag&unix.PARENB == 0:
+		conf.Parity = ParityNone
+	case tty.Cflag&unix.PARODD != 0:
+		conf.Parity = ParityOdd
+	default:
+		conf.Parity = ParityEven
+	}
+	conf.StopBits = StopBits1
+	if tty.Cflag&unix.CSTOPB != 0 {
+		conf.StopBits = StopBits2
+	}
+	conf.FlowControl = termiosFlowControl(tty)
+	conf.XonChar, conf.XoffChar = tty.Cc[unix.VSTART], tty.Cc[unix.VSTOP]
+	conf.HonorModemControl = tty.Cflag&unix.CLOCAL == 0
+
+	return conf, nil
+}
+
+// termiosBaudRate returns the baud rate set in tty. Under a custom divisor,
+// termios reports 38400 and the rate is taken to be the configured one.
+func (p *port) termiosBaudRate(tty *unix.Termios) (int, bool) {
+	speed := tty.Cflag & unix.CBAUD
+	if speed == unix.BOTHER {
+		return int(tty.Ospeed), true
+	}
+	if speed == unix.B38400 {
+		if ss, err := getSerialStruct(p.fd); err == nil && ss.Flags&asyncSpdMask == asyncSpdCust {
+			return p.conf.BaudRate, true
+		}
+	}
+	for rate, b := range baudRates {
+		if rate != 0 && b == speed {
+			return rate, true
+		}
+	}
+	return 0, false
+}
+
+// Config returns the configuration the port was opened with, as applied.
+func (p *port) Config() (Config, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return Config{}, ErrPortClosed
+	}
+	return p.conf, nil
+}
+
+// inputWaiting returns the number of bytes received but not yet read.
+func (p *port) inputWaiting() (int, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return 0, ErrPortClosed
+	}
+	return unix.IoctlGetInt(p.fd, unix.TIOCINQ)
+}
+
+func (p *port) lineErrors() (LineErrorCounts, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return LineErrorCounts{}, ErrPortClosed
+	}
+
+	p.lineErrsMut.Lock()
+	defer p.lineErrsMut.Unlock()
+
+	c, err := sysGetICounter(p.fd)
+	if err != nil {
+		return LineErrorCounts{}, err
+	}
+
+	prev := p.lineErrs
+	p.lineErrs = c
+
+	return LineErrorCounts{
+		Parity:  int(c.Parity - prev.Parity),
+		Framing: int(c.Frame - prev.Frame),
+		Overrun: int(c.Overrun - prev.Overrun + c.BufOverrun - prev.BufOverrun),
+		Break:   int(c.Brk - prev.Brk),
+	}, nil
+}
+
+// outputWaiting returns the number of bytes written but not yet transmitted.
+func (p *port) outputWaiting() (int, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return 0, ErrPortClosed
+	}
+	return unix.IoctlGetInt(p.fd, unix.TIOCOUTQ)
+}
+
+func (p *port) modemStatus() (ModemStatus, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return ModemStatus{}, ErrPortClosed
+	}
+
+	lines, err := unix.IoctlGetInt(p.fd, unix.TIOCMGET)
+	if err != nil {
+		return ModemStatus{}, err
+	}
+
+	return ModemStatus{
+		CTS: lines&unix.TIOCM_CTS != 0,
+		DSR: lines&unix.TIOCM_DSR != 0,
+		RI:  lines&unix.TIOCM_RNG != 0,
+		DCD: lines&unix.TIOCM_CD != 0,
+	}, nil
+}
+
+// eventWatch is what waitEvents last saw of the port.
+type eventWatch struct {
+	started bool
+	input   int            // bytes waiting to be read
+	counts  serialICounter // line error counts, if counted
+	counted bool           // the driver supports TIOCGICOUNT
+}
+
+// waitEvents waits up to maxReadWait for bytes to arrive, and returns the
+// events that happened since the last call with w. Input is only waited for
+// while none is waiting, as poll would report it at once; more bytes arriving
+// on top are noticed on the next call.
+func (p *port) waitEvents(ctx context.Context, w *eventWatch) ([]Event, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return nil, ErrPortClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !w.started {
+		c, err := sysGetICounter(p.fd)
+		w.started, w.counts, w.counted = true, c, err == nil
+	}
+
+	fds := []unix.PollFd{{Fd: int32(p.closeSignal.ReadFD()), Events: unix.POLLIN}}
+	if w.input == 0 {
+		fds = append(fds, unix.PollFd{Fd: int32(p.fd), Events: unix.POLLIN})
+	}
+	ts := unix.NsecToTimespec(int64(maxReadWait))
+	if _, err := unix.Ppoll(fds, &ts, nil); err != nil && err != unix.EINTR {
+		return nil, err
+	}
+	if fds[0].Revents != 0 {
+		return nil, ErrPortClosed
+	}
+	if len(fds) > 1 && fds[1].Revents&(unix.POLLHUP|unix.POLLERR) != 0 {
+		return nil, p.hangupErr(nil)
+	}
+
+	var events []Event
+
+	n, err := unix.IoctlGetInt(p.fd, unix.TIOCINQ)
+	if err != nil {
+		return nil, err
+	}
+	if n > w.input {
+		events = append(events, EventDataAvailable)
+	}
+	w.input = n
+
+	if w.counted {
+		c, err := sysGetICounter(p.fd)
+		if err != nil {
+			return nil, err
+		}
+		prev := w.counts
+		w.counts = c
+
+		if c.Parity != prev.Parity || c.Frame != prev.Frame || c.Overrun != prev.Overrun || c.BufOverrun != prev.BufOverrun {
+			events = append(events, EventLineError)
+		}
+		if c.Brk != prev.Brk {
+			events = append(events, EventBreakReceived)
+		}
+	}
+
+	return events, nil
+}
+
+// modemLines is a snapshot of the modem status lines, compared by
+// waitModemChange.
+type modemLines struct {
+	lines   int
+	changes [4]int32 // CTS, DSR, RI and DCD transitions, if counted
+}
+
+// waitModemChange waits for a modem status line to change, or ctx to be done.
+// TIOCMIWAIT cannot be interrupted, and a wait left blocked in it would keep
+// the tty open past Close, so the lines are polled every maxReadWait instead,
+// waiting on the close signal in between. Where the driver counts
+// transitions with TIOCGICOUNT, as TIOCMIWAIT does, pulses shorter than that
+// are still seen.
+func (p *port) waitModemChange(ctx context.Context) error {
+	initial, err := p.pollModemLines(0)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ml, err := p.pollModemLines(maxReadWait)
+		if err != nil {
+			return err
+		}
+		if ml != initial {
+			return nil
+		}
+	}
+}
+
+// pollModemLines waits for wait, returning ErrPortClosed if Close signals
+// meanwhile, then takes a snapshot of the modem status lines.
+func (p *port) pollModemLines(wait time.Duration) (modemLines, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return modemLines{}, ErrPortClosed
+	}
+
+	if wait > 0 {
+		fds := []unix.PollFd{{Fd: int32(p.closeSignal.ReadFD()), Events: unix.POLLIN}}
+		ts := unix.NsecToTimespec(int64(wait))
+		if _, err := unix.Ppoll(fds, &ts, nil); err != nil && err != unix.EINTR {
+			return modemLines{}, err
+		}
+		if fds[0].Revents != 0 {
+			return modemLines{}, ErrPortClosed
+		}
+	}
+
+	lines, err := unix.IoctlGetInt(p.fd, unix.TIOCMGET)
+	if err != nil {
+		return modemLines{}, err
+	}
+
+	ml := modemLines{lines: lines & (unix.TIOCM_CTS | unix.TIOCM_DSR | unix.TIOCM_RNG | unix.TIOCM_CD)}
+	if c, err := sysGetICounter(p.fd); err == nil {
+		ml.changes = [4]int32{c.CTS, c.DSR, c.RNG, c.DCD}
+	}
+	return ml, nil
+}
+
+// supportsBaudRate checks baudRate against the standard rates and, if the
+// port is open and its driver reports the UART's base rate, against the rates
+// the UART can be clocked at, custom divisors included. Drivers that do not
+// report a base rate are taken to accept any rate set with BOTHER.
+func (p *port) supportsBaudRate(baudRate int) bool {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	_, standard := baudRates[baudRate]
+
+	if p.isClosing() || p.fd == -1 {
+		return standard
+	}
+	if isCDCACM(p.path) {
+		return true // the baud rate is ignored, see open
+	}
+
+	ss, err := getSerialStruct(p.fd)
+	if err != nil || ss.BaudBase <= 0 {
+		return standard || (baudRate > 0 && hasTermios2(p.fd))
+	}
+	if baudRate > int(ss.BaudBase) {
+		return false
+	}
+	if standard {
+		return true
+	}
+
+	_, err = customDivisor(int(ss.BaudBase), baudRate)
+	return err == nil
+}
+
+// flush discards bytes received but not yet read if input is set, and bytes
+// written but not yet transmitted if output is set.
+func (p *port) flush(input, output bool) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return ErrPortClosed
+	}
+
+	var queue int
+	switch {
+	case input && output:
+		queue = unix.TCIOFLUSH
+	case input:
+		queue = unix.TCIFLUSH
+	case output:
+		queue = unix.TCOFLUSH
+	default:
+		return nil
+	}
+	return unix.IoctlSetInt(p.fd, unix.TCFLSH, queue)
+}
+
+// drain blocks until bytes written have been transmitted. tcdrain has no
+// timeout, so it waits on a copy of the fd without holding the lock, and Close
+// discards the output it is waiting on to end it.
+func (p *port) drain() error {
+	p.mut.RLock()
+	if p.isClosing() || p.fd == -1 {
+		p.mut.RUnlock()
+		return ErrPortClosed
+	}
+	fd, err := unix.FcntlInt(uintptr(p.fd), unix.F_DUPFD_CLOEXEC, 0)
+	p.mut.RUnlock()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	p.closingMut.Lock()
+	if p.closing {
+		p.closingMut.Unlock()
+		return ErrPortClosed
+	}
+	p.draining = true
+	p.closingMut.Unlock()
+
+	// TCSBRK with a non-zero argument is tcdrain, not a break
+	err = unix.IoctlSetInt(fd, unix.TCSBRK, 1)
+
+	p.closingMut.Lock()
+	p.draining = false
+	closing := p.closing
+	p.closingMut.Unlock()
+
+	if closing {
+		return ErrPortClosed
+	}
+	return err
+}
+
+func (p *port) setBreak(on bool) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return ErrPortClosed
+	}
+
+	req := uint(unix.TIOCCBRK)
+	if on {
+		req = unix.TIOCSBRK
+	}
+	return ioctl(p.fd, req, nil)
+}
+
+// SetDTR asserts or deasserts DTR. Ptys have no modem lines and fail with
+// ENOTTY.
+func (p *port) SetDTR(on bool) error {
+	return p.setModemLines(unix.TIOCM_DTR, on)
+}
+
+// SetRTS asserts or deasserts RTS, e.g. to drive the direction of an RS-485
+// transceiver. Under RTS/CTS flow control, the driver may change it again.
+// Ptys have no modem lines and fail with ENOTTY.
+func (p *port) SetRTS(on bool) error {
+	return p.setModemLines(unix.TIOCM_RTS, on)
+}
+
+// setModemLines asserts or deasserts the modem lines in bits, leaving the
+// others alone.
+func (p *port) setModemLines(bits int, on bool) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.fd == -1 {
+		return ErrPortClosed
+	}
+
+	req := uint(unix.TIOCMBIC)
+	if on {
+		req = unix.TIOCMBIS
+	}
+	return unix.IoctlSetPointerInt(p.fd, req, bits)
+}
+
+// reopen opens the port again after it has been closed.
+func (p *port) reopen() error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if err := p.open(); err != nil {
+		return err
+	}
+
+	p.closingMut.Lock()
+	p.closing = false
+	p.closingMut.Unlock()
+
+	return nil
+}
+
+// setBaudRate sets conf.BaudRate on tty or, if it is not supported, the first
+// supported rate in conf.BaudRateFallback, which then replaces conf.BaudRate.
+func (p *port) setBaudRate(fd int, tty *unix.Termios) error {
+	conf := &p.conf
+
+	var firstErr error
+	for _, rate := range append([]int{conf.BaudRate}, conf.BaudRateFallback...) {
+		err := termiosSetBaudrate(tty, rate)
+		switch {
+		case err == nil:
+			clearCustomDivisor(fd)
+		case termiosSetCustomBaudrate(fd, tty, rate) == nil:
+			clearCustomDivisor(fd)
+		case setCustomDivisor(fd, rate) == nil:
+			// the UART runs at baud_base/custom_divisor whenever termios asks for 38400
+			tty.Cflag &^= unix.CBAUD
+			tty.Cflag |= unix.B38400
+		default:
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if rate != conf.BaudRate {
+			conf.logf("serial: %s does not support %d baud, falling back to %d", p.path, conf.BaudRate, rate)
+			conf.BaudRate = rate
+		}
+		return nil
+	}
+
+	if isCDCACM(p.path) {
+		// CDC-ACM devices ignore the baud rate, don't fail on one they don't care about
+		conf.logf("serial: %s is a CDC-ACM device, ignoring %v", p.path, firstErr)
+		return nil
+	}
+	return firstErr
+}
+
+// setLineLevels puts DTR and RTS at dtr and rts, LineLevelNil leaving a line
+// untouched, setting both with one ioctl.
+func setLineLevels(fd int, dtr, rts LineLevel) error {
+	if dtr == LineLevelNil && rts == LineLevelNil {
+		return nil // by default the lines are left untouched
+	}
+
+	status, err := unix.IoctlGetInt(fd, unix.TIOCMGET)
+	if err != nil {
+		return fmt.Errorf("error getting modem lines: %w", err)
+	}
+
+	for _, l := range []struct {
+		level LineLevel
+		bit   int
+	}{{dtr, unix.TIOCM_DTR}, {rts, unix.TIOCM_RTS}} {
+		switch l.level {
+		case LineLevelHigh:
+			status |= l.bit
+		case LineLevelLow:
+			status &^= l.bit
+		}
+	}
+
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCMSET, status); err != nil {
+		return fmt.Errorf("error setting modem lines: %w", err)
+	}
+	return nil
+}
+
+func termiosSetRaw(tty *unix.Termios) {
+	tty.Cflag |= unix.CREAD  // enable receiver
+	tty.Cflag |= unix.CLOCAL // ignore modem control lines
+
+	tty.Oflag &^= unix.OPOST // disable output processing
+
+	tty.Lflag &^= unix.ECHO   // don't echo input
+	tty.Lflag &^= unix.ISIG   // don't interpret INTR, SUSP, DSUSP and QUIT characters
+	tty.Lflag &^= unix.ICANON // disable canonical mode
+	tty.Lflag &^= unix.IEXTEN // disable implementation-defined input processing
+	// disable echo handling, shouldn't be necessary as ECHO bit is off but just in case
+	tty.Lflag &^= unix.ECHOE
+	tty.Lflag &^= unix.ECHOK
+	tty.Lflag &^= unix.ECHONL
+	tty.Lflag &^= unix.ECHOCTL
+	tty.Lflag &^= unix.ECHOPRT
+	tty.Lflag &^= unix.ECHOKE
+
+	// disable software flow control
+	tty.Iflag &^= unix.IXON | unix.IXOFF | unix.IXANY
+
+	// disable special handling of received bytes
+	tty.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL
+}
+
+func termiosSetBaudrate(tty *unix.Termios, baudRate int) error {
+	b, ok := baudRates[baudRate]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnsupportedBaudRate, baudRate)
+	}
+	tty.Cflag &^= unix.CBAUD
+	tty.Cflag |= b
+	return nil
+}
+
+// termiosSetCustomBaudrate sets baudRate as is with BOTHER, for rates with no
+// Bfoo constant. It fails if the kernel lacks termios2, leaving older ways of
+// setting custom rates to be tried.
+func termiosSetCustomBaudrate(fd int, tty *unix.Termios, baudRate int) error {
+	if baudRate <= 0 || uint64(baudRate) > math.MaxUint32 {
+		return fmt.Errorf("%w: %d", ErrUnsupportedBaudRate, baudRate)
+	}
+	if !hasTermios2(fd) {
+		return fmt.Errorf("%w: %d, no termios2", ErrUnsupportedBaudRate, baudRate)
+	}
+
+	tty.Cflag &^= unix.CBAUD | unix.CIBAUD // input speed follows output speed
+	tty.Cflag |= unix.BOTHER
+	tty.Ispeed = uint32(baudRate)
+	tty.Ospeed = uint32(baudRate)
+	return nil
+}
+
+func hasTermios2(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+	return err == nil
+}
+
+// setCustomDivisor approximates baudRate on UARTs that predate BOTHER by
+// setting a custom divisor of the UART's base clock (setserial's spd_cust),
+// which takes effect when the termios speed is 38400.
+func setCustomDivisor(fd int, baudRate int) error {
+	if baudRate <= 0 {
+		return fmt.Errorf("%w: %d", ErrUnsupportedBaudRate, baudRate)
+	}
+
+	ss, err := getSerialStruct(fd)
+	if err != nil {
+		return err
+	}
+
+	divisor, err := customDivisor(int(ss.BaudBase), baudRate)
+	if err != nil {
+		return err
+	}
+
+	ss.Flags = ss.Flags&^asyncSpdMask | asyncSpdCust
+	ss.CustomDivisor = int32(divisor)
+
+	return setSerialStruct(fd, &ss)
+}
+
+// customDivisor returns the divisor of baseRate closest to baudRate, or an
+// error if the resulting rate is more than 2% off.
+func customDivisor(baseRate, baudRate int) (int, error) {
+	const maxError = 0.02
+
+	divisor := (baseRate + baudRate/2) / baudRate
+	if divisor == 0 {
+		return 0, fmt.Errorf("baud rate %d exceeds UART base rate %d", baudRate, baseRate)
+	}
+
+	actual := baseRate / divisor
+	if diff := float64(actual-baudRate) / float64(baudRate); diff > maxError || diff < -maxError {
+		return 0, fmt.Errorf("baud rate %d cannot be derived from UART base rate %d", baudRate, baseRate)
+	}
+
+	return divisor, nil
+}
+
+// clearCustomDivisor undoes setCustomDivisor, so a standard rate of 38400
+// isn't silently remapped. Ports without serial_struct support are ignored.
+func clearCustomDivisor(fd int) {
+	ss, err := getSerialStruct(fd)
+	if err != nil || ss.Flags&asyncSpdMask != asyncSpdCust {
+		return
+	}
+
+	ss.Flags &^= asyncSpdMask
+	ss.CustomDivisor = 0
+
+	setSerialStruct(fd, &ss)
+}
+
+// rs485 converts c to its serial_rs485 form.
+func rs485(c RS485) *serialRS485 {
+	rs := &serialRS485{
+		Flags:              serRS485Enabled,
+		DelayRTSBeforeSend: uint32(c.DelayBeforeSend.Milliseconds()),
+		DelayRTSAfterSend:  uint32(c.DelayAfterSend.Milliseconds()),
+	}
+	if c.RTSOnSend {
+		rs.Flags |= serRS485RTSOnSend
+	}
+	if c.RTSAfterSend {
+		rs.Flags |= serRS485RTSAfterSend
+	}
+	return rs
+}
+
+// setLowLatency sets ASYNC_LOW_LATENCY, so the driver pushes received bytes
+// to the tty without delay.
+func setLowLatency(fd int) error {
+	ss, err := getSerialStruct(fd)
+	if err != nil {
+		return err
+	}
+	if ss.Flags&asyncLowLatency != 0 {
+		return nil
+	}
+
+	ss.Flags |= asyncLowLatency
+	return setSerialStruct(fd, &ss)
+}
+
+// termiosSetRequest returns the ioctl request applying termios in mode. Custom
+// baud rates (BOTHER) need the termios2 requests, which carry the speed.
+func termiosSetRequest(mode ApplyMode, bother bool) (uint, error) {
+	switch mode {
+	case ApplyModeNow, ApplyModeNil:
+		if bother {
+			return unix.TCSETS2, nil
+		}
+		return unix.TCSETS, nil
+	case ApplyModeDrain:
+		if bother {
+			return unix.TCSETSW2, nil
+		}
+		return unix.TCSETSW, nil
+	case ApplyModeFlush:
+		if bother {
+			return unix.TCSETSF2, nil
+		}
+		return unix.TCSETSF, nil
+	default:
+		return 0, fmt.Errorf("unsupported apply mode: %v", mode)
+	}
+}
+
+func termiosSetCharSize(tty *unix.Termios, charSize int) error {
+	s, ok := charSizes[charSize]
+	if !ok {
+		return fmt.Errorf("unsupported character size: %d", charSize)
+	}
+	tty.Cflag &^= unix.CSIZE
 	tty.Cflag |= s
 	return nil
 }
 
+// termiosSetFlowControl sets the flow control mode. Under software flow
+// control, xon and xoff replace DC1 and DC3 if not zero.
+func termiosSetFlowControl(tty *unix.Termios, fc FlowControl, xon, xoff byte) error {
+	switch fc {
+	case FlowControlNone, FlowControlNil:
+		// termiosSetRaw disables software flow control
+		tty.Cflag &^= unix.CRTSCTS
+	case FlowControlRTSCTS:
+		tty.Cflag |= unix.CRTSCTS
+	case FlowControlXONXOFF:
+		tty.Cflag &^= unix.CRTSCTS
+		tty.Iflag |= unix.IXON | unix.IXOFF
+		tty.Cc[unix.VSTART] = orDefault(xon, DefaultXonChar)
+		tty.Cc[unix.VSTOP] = orDefault(xoff, DefaultXoffChar)
+	default:
+		return fmt.Errorf("unsupported flow control: %v", fc)
+	}
+	return nil
+}
+
 func termiosSetStopBits(tty *unix.Termios, stopBits StopBits) error {
 	switch stopBits {
 	case StopBits1, StopBitsNil:
@@ -288,6 +1637,46 @@ func termiosSetParity(tty *unix.Termios, parity Parity) error {
 	return nil
 }
 
+// termiosSetAddressBit enables address bit mode, which takes over the bit
+// parity would occupy.
+func termiosSetAddressBit(tty *unix.Termios) {
+	tty.Cflag &^= unix.PARENB | unix.PARODD
+	tty.Iflag &^= unix.INPCK
+	tty.Cflag |= addrb
+}
+
+func termiosSetHangupOnClose(tty *unix.Termios, hangup bool) {
+	if hangup {
+		return // leave HUPCL as configured by the system
+	}
+	tty.Cflag &^= unix.HUPCL // don't lower modem control lines on last close
+}
+
+// vtime returns gap in deciseconds, rounded up. Without a gap, it is zero if
+// atLeast bytes are wanted, as poll only waits for VMIN bytes if VTIME is
+// zero, and tickResolution otherwise.
+func vtime(gap time.Duration, atLeast int) byte {
+	switch {
+	case gap <= 0 && atLeast > 0:
+		return 0
+	case gap <= 0:
+		return tickResolution
+	}
+	ds := (gap + 100*time.Millisecond - 1) / (100 * time.Millisecond)
+	if ds > 0xff {
+		return 0xff
+	}
+	return byte(ds)
+}
+
+// vmin returns n capped to what VMIN can hold.
+func vmin(n int) byte {
+	if n > 0xff {
+		return 0xff
+	}
+	return byte(n)
+}
+
 func termiosSetTimeout(tty *unix.Termios, vtime, vmin byte) {
 	tty.Cc[unix.VTIME] = vtime
 	tty.Cc[unix.VMIN] = vmin
@@ -3,19 +3,24 @@
 package serial_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/shasderias/serial"
+	"golang.org/x/sys/unix"
 )
 
-func startSocat(t *testing.T, args ...string) {
+func startSocat(t testing.TB, args ...string) {
 	_, err := exec.LookPath("socat")
 	if err != nil {
 		t.Skip("socat not found in path")
@@ -53,7 +58,7 @@ func startSocat(t *testing.T, args ...string) {
 	}
 }
 
-func setupLoopbackPorts(t *testing.T) (string, string) {
+func setupLoopbackPorts(t testing.TB) (string, string) {
 	var (
 		tempDir = t.TempDir()
 
@@ -141,6 +146,65 @@ func TestConfigureTTY(t *testing.T) {
 	}
 }
 
+func TestRestoreOnClose(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	setICanonCmd := exec.Command("stty", "-F", portPath, "icanon")
+	if err := setICanonCmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.RestoreOnClose = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := port.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("stty", "-F", portPath).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(out), "-icanon") {
+		t.Fatal("tty left in non-canonical mode; want original settings restored")
+	}
+}
+
+func TestKeepSettingsOnClose(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		portPath, _ := setupLoopbackPorts(t)
+
+		if err := exec.Command("stty", "-F", portPath, "icanon").Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		port, err := serial.Open(portPath, func(c *serial.Config) {
+			c.BaudRate = baudRate
+			c.KeepSettingsOnClose = keep
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := port.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := exec.Command("stty", "-F", portPath).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if raw := strings.Contains(string(out), "-icanon"); raw != keep {
+			t.Fatalf("KeepSettingsOnClose %t: got tty left in non-canonical mode %t", keep, raw)
+		}
+	}
+}
+
 func printSTTY(t *testing.T, path string) {
 	sttyCmd := exec.Command("stty", "-F", path)
 	out, err := sttyCmd.Output()
@@ -149,3 +213,474 @@ func printSTTY(t *testing.T, path string) {
 	}
 	t.Log(string(out))
 }
+
+func TestTermiosRoundTrip(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	p, ok := port1.(interface {
+		Termios() (*unix.Termios, error)
+		SetTermios(*unix.Termios) error
+	})
+	if !ok {
+		t.Fatal("port does not expose termios")
+	}
+
+	tty, err := p.Termios()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tty.Iflag&unix.IXON != 0 {
+		t.Fatal("IXON set on a raw port")
+	}
+
+	tty.Iflag |= unix.IXON
+	if err := p.SetTermios(tty); err != nil {
+		t.Fatal(err)
+	}
+
+	tty, err = p.Termios()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tty.Iflag&unix.IXON == 0 {
+		t.Fatal("got IXON clear; want set by SetTermios")
+	}
+
+	if fc, err := port1.FlowControl(); err != nil || fc != serial.FlowControlXONXOFF {
+		t.Fatalf("got %v, %v; want %v read back", fc, err, serial.FlowControlXONXOFF)
+	}
+}
+
+func TestDevicePath(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	p, ok := port.(interface{ DevicePath() (string, error) })
+	if !ok {
+		t.Fatal("port does not expose its device path")
+	}
+
+	got, err := p.DevicePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := filepath.EvalSymlinks(portPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
+
+func TestAbortOnErrorUnsupported(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.AbortOnError = true
+	})
+	if err == nil {
+		port.Close()
+	}
+	if !errors.Is(err, serial.ErrUnsupported) {
+		t.Fatalf("got %v; want %v", err, serial.ErrUnsupported)
+	}
+}
+
+func TestDisableParityCheck(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.Parity = serial.ParityEven
+		c.DisableParityCheck = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	tty, err := port.(interface{ Termios() (*unix.Termios, error) }).Termios()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ptys clear PARENB, so only the check can be verified here
+	if tty.Iflag&unix.INPCK != 0 {
+		t.Fatal("got INPCK set; want parity not checked")
+	}
+}
+
+func TestXonXoffChars(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.FlowControl = serial.FlowControlXONXOFF
+		c.XoffChar = 0x14
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	tty, err := port.(interface{ Termios() (*unix.Termios, error) }).Termios()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tty.Cc[unix.VSTART] != serial.DefaultXonChar || tty.Cc[unix.VSTOP] != 0x14 {
+		t.Fatalf("got XON %#x, XOFF %#x; want %#x, %#x", tty.Cc[unix.VSTART], tty.Cc[unix.VSTOP], serial.DefaultXonChar, 0x14)
+	}
+}
+
+func TestCustomBaudRate(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	const rate = 250000
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = rate
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	fd, err := unix.Open(portPath, unix.O_RDONLY|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fd)
+
+	tty, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tty.Cflag&unix.CBAUD != unix.BOTHER || tty.Ospeed != rate {
+		t.Fatalf("got cflag %#o, ospeed %d; want BOTHER, %d", tty.Cflag&unix.CBAUD, tty.Ospeed, rate)
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = serial.Baud9600
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	if err := port.Reconfigure(func(c *serial.Config) {
+		c.BaudRate = serial.Baud115200
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tty, err := port.(interface{ Termios() (*unix.Termios, error) }).Termios()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tty.Cflag&unix.CBAUD != unix.B115200 {
+		t.Fatalf("got cflag %#o; want B115200", tty.Cflag&unix.CBAUD)
+	}
+
+	err = port.Reconfigure(func(c *serial.Config) {
+		c.BaudRate = serial.Baud9600
+		c.XonChar, c.XoffChar = 'x', 'x'
+	})
+	if err == nil {
+		t.Fatal("got nil error; want invalid configuration rejected")
+	}
+
+	conf, err := port.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.BaudRate != serial.Baud115200 {
+		t.Fatalf("got baud rate %d; want %d", conf.BaudRate, serial.Baud115200)
+	}
+
+	// ptys reject RS-485 mode, after the new termios has been applied
+	err = port.Reconfigure(func(c *serial.Config) {
+		c.BaudRate = serial.Baud9600
+		c.RS485.Enabled = true
+	})
+	if err == nil {
+		t.Skip("port supports RS-485 mode")
+	}
+
+	tty, err = port.(interface{ Termios() (*unix.Termios, error) }).Termios()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tty.Cflag&unix.CBAUD != unix.B115200 {
+		t.Fatalf("got cflag %#o after failed reconfigure; want B115200", tty.Cflag&unix.CBAUD)
+	}
+}
+
+func TestCurrentConfig(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	// all defaults
+	port, err := serial.Open(portPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	conf, err := port.CurrentConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.BaudRate != serial.Baud19200 || conf.DataBits != 8 || conf.StopBits != serial.StopBits1 {
+		t.Fatalf("got %d baud, %d data bits, stop bits %v; want 19200, 8, %v",
+			conf.BaudRate, conf.DataBits, conf.StopBits, serial.StopBits1)
+	}
+	if conf.FlowControl != serial.FlowControlNone {
+		t.Fatalf("got flow control %v; want %v", conf.FlowControl, serial.FlowControlNone)
+	}
+
+	port.Close()
+	if _, err := port.CurrentConfig(); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestHonorModemControl(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	for _, honor := range []bool{false, true} {
+		port, err := serial.Open(portPath, func(c *serial.Config) {
+			c.BaudRate = baudRate
+			c.HonorModemControl = honor
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tty, err := port.(interface{ Termios() (*unix.Termios, error) }).Termios()
+		port.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if clocal := tty.Cflag&unix.CLOCAL != 0; clocal == honor {
+			t.Errorf("HonorModemControl %v: got CLOCAL %v; want %v", honor, clocal, !honor)
+		}
+	}
+}
+
+func TestLowLatencyUnsupported(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	var logged bool
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.LowLatency = true
+		c.Logf = func(format string, v ...any) { logged = true }
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	// ptys have no serial_struct
+	if !logged {
+		t.Fatal("got no note; want low latency mode reported unsupported")
+	}
+}
+
+func TestRS485Rejected(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.RS485 = serial.RS485{Enabled: true, RTSOnSend: true}
+	})
+	if err == nil {
+		port.Close()
+	}
+	// ptys do not support RS-485 mode
+	if err == nil || !strings.Contains(err.Error(), "RS-485") {
+		t.Fatalf("got %v; want RS-485 mode rejected", err)
+	}
+}
+
+func TestShortReadDeadline(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	const timeout = 5 * time.Millisecond
+
+	start := time.Now()
+	if err := port2.SetReadDeadline(start.Add(timeout)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := port2.Read(make([]byte, 1))
+	elapsed := time.Since(start)
+
+	if err != os.ErrDeadlineExceeded {
+		t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+	// well short of the 100ms of a VTIME tick, with slack for a loaded machine
+	if elapsed < timeout || elapsed > 50*time.Millisecond {
+		t.Fatalf("read returned after %v; want about %v", elapsed, timeout)
+	}
+}
+
+func TestOpenFailureClosesFd(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	fds := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	before := fds()
+	// fails once the pty is open, as ptys do not support RS-485 mode
+	if _, err := serial.Open(portPath, func(c *serial.Config) {
+		c.RS485 = serial.RS485{Enabled: true}
+	}); err == nil {
+		t.Fatal("opened with RS-485 mode")
+	}
+	if after := fds(); after != before {
+		t.Fatalf("got %d open fds; want %d", after, before)
+	}
+}
+
+func TestExclusive(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.Exclusive = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	if os.Geteuid() != 0 {
+		if _, err := serial.Open(portPath); !errors.Is(err, serial.ErrPortInUse) {
+			t.Fatalf("got %v; want %v", err, serial.ErrPortInUse)
+		}
+		return
+	}
+
+	// root bypasses TIOCEXCL, so check the flag instead
+	fd, err := unix.Open(portPath, unix.O_RDONLY|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fd)
+
+	excl, err := unix.IoctlGetInt(fd, unix.TIOCGEXCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if excl == 0 {
+		t.Fatal("got TIOCEXCL unset; want set")
+	}
+}
+
+func TestNonBlockingFD(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.BaudRate = baudRate
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	dev, err := port.(interface{ DevicePath() (string, error) }).DevicePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// find the port's fd among ours by the device it refers to
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fd = -1
+	for _, e := range fds {
+		if target, err := os.Readlink(filepath.Join("/proc/self/fd", e.Name())); err == nil && target == dev {
+			fd, _ = strconv.Atoi(e.Name())
+		}
+	}
+	if fd == -1 {
+		t.Fatal("port fd not found")
+	}
+
+	flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags&unix.O_NONBLOCK == 0 {
+		t.Fatal("got blocking fd; want O_NONBLOCK kept")
+	}
+}
+
+func TestWriteContextWakesBackoff(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	port, err := serial.Open(portPath, func(c *serial.Config) {
+		c.Backoff = func() serial.Backoff { return serial.NewFixedBackoff(time.Hour) }
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// nothing reads the other end, so the write fills the buffers and backs off
+	start := time.Now()
+	_, err = port.WriteContext(ctx, make([]byte, 1<<20))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got %v; want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("write returned after %v; want it woken from its backoff", elapsed)
+	}
+}
+
+// skipIfNoModemLines skips t if err is the error ptys, which have no modem
+// lines, fail modem line requests with.
+func skipIfNoModemLines(t *testing.T, err error) {
+	t.Helper()
+
+	if errors.Is(err, unix.ENOTTY) {
+		t.Skipf("no modem lines: %v", err)
+	}
+}
+
+// skipIfNoErrorCounts skips t if err is the error ptys, which do not count
+// line errors, fail TIOCGICOUNT with. TestFaultLineErrors covers them instead.
+func skipIfNoErrorCounts(t *testing.T, err error) {
+	t.Helper()
+
+	if errors.Is(err, unix.ENOTTY) {
+		t.Skipf("no line error counts: %v", err)
+	}
+}
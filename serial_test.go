@@ -1,8 +1,13 @@
 package serial_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io"
+	"math"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +18,10 @@ import (
 const (
 	shortSleepDuration = 10 * time.Millisecond
 	longSleepDuration  = 1 * time.Second
+
+	// closeUnblockDuration bounds how long Close takes to unblock a pending
+	// Read.
+	closeUnblockDuration = 100 * time.Millisecond
 )
 
 const (
@@ -21,6 +30,10 @@ const (
 	parity     = serial.ParityEven
 	stopBits   = serial.StopBits1
 	testString = "hello world"
+
+	// tooFastBaudRate does not fit the speed fields of termios2 or a DCB, so
+	// no port accepts it.
+	tooFastBaudRate = math.MaxInt
 )
 
 func TestSanity(t *testing.T) {
@@ -266,6 +279,262 @@ func TestSerialReadAndCloseConcurrency(t *testing.T) {
 	port.Close()
 }
 
+func TestCloseUnblocksRead(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port2.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := port1.Read(make([]byte, 1))
+		errc <- err
+	}()
+
+	time.Sleep(shortSleepDuration)
+	if err := port1.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, serial.ErrPortClosed) {
+			t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+		}
+	case <-time.After(closeUnblockDuration):
+		t.Fatal("read still blocked after Close")
+	}
+}
+
+func TestReadByte(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if _, err := port1.Write([]byte("ab")); err != nil {
+		t.Fatal(err)
+	}
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []byte("ab") {
+		if c, err := port2.ReadByte(); err != nil || c != want {
+			t.Fatalf("got %q, %v; want %q, nil", c, err, want)
+		}
+	}
+
+	if err := port2.SetReadDeadline(time.Now().Add(shortSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port2.ReadByte(); err != os.ErrDeadlineExceeded {
+		t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	port2.Close()
+	if _, err := port2.ReadByte(); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestWriteString(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if n, err := port1.WriteString(testString); err != nil || n != len(testString) {
+		t.Fatalf("got %d, %v; want %d, nil", n, err, len(testString))
+	}
+
+	buf := make([]byte, len(testString))
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port2.Read(buf); err != nil || string(buf) != testString {
+		t.Fatalf("read %q, %v; want %q, nil", buf, err, testString)
+	}
+
+	if allocs := testing.AllocsPerRun(10, func() { port1.WriteString("x") }); allocs != 0 {
+		t.Fatalf("got %v allocations per WriteString; want 0", allocs)
+	}
+}
+
+func TestTryRead(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	// an expired deadline does not apply
+	if err := port2.SetReadDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 8)
+	if n, err := port2.TryRead(buf); n != 0 || err != nil {
+		t.Fatalf("got %d, %v; want 0, nil", n, err)
+	}
+
+	if _, err := port1.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	n, err := port2.TryRead(buf)
+	if err != nil || string(buf[:n]) != "abc" {
+		t.Fatalf("read %q, %v; want %q, nil", buf[:n], err, "abc")
+	}
+}
+
+func TestReadMinBytes(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if err := port2.Reconfigure(func(c *serial.Config) { c.ReadMinBytes = 2 }); err != nil {
+		t.Fatal(err)
+	}
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := port1.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 8)
+	n, err := port2.Read(buf)
+	if err != nil || n < 2 {
+		t.Fatalf("read %q, %v; want at least 2 bytes, nil", buf[:n], err)
+	}
+}
+
+func TestReadInterByteTimeout(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if err := port2.Reconfigure(func(c *serial.Config) { c.ReadInterByteTimeout = 50 * time.Millisecond }); err != nil {
+		t.Fatal(err)
+	}
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := port1.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 8)
+	n, err := port2.Read(buf)
+	if err != nil || string(buf[:n]) != "abc" {
+		t.Fatalf("read %q, %v; want %q, nil", buf[:n], err, "abc")
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := port1.ReadContext(ctx, make([]byte, 1))
+		errc <- err
+	}()
+
+	time.Sleep(shortSleepDuration)
+	cancel()
+
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Fatalf("got %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(closeUnblockDuration):
+		t.Fatal("read still blocked after cancel")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), shortSleepDuration)
+	defer cancel()
+	if _, err := port1.ReadContext(ctx, make([]byte, 1)); err != context.DeadlineExceeded {
+		t.Fatalf("got %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	// the port's own deadline still applies
+	if err := port1.SetReadDeadline(time.Now().Add(shortSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port1.ReadContext(context.Background(), make([]byte, 1)); err != os.ErrDeadlineExceeded {
+		t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if n, err := port1.WriteContext(context.Background(), []byte(testString)); err != nil || n != len(testString) {
+		t.Fatalf("got %d, %v; want %d, nil", n, err, len(testString))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := port1.WriteContext(ctx, []byte(testString)); err != context.Canceled {
+		t.Fatalf("got %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	const readers, size = 4, 16
+
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mut  sync.Mutex
+		seen = make(map[byte]int)
+	)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			buf := make([]byte, size)
+			n, err := port2.Read(buf)
+			if err != nil {
+				t.Error(err)
+			}
+
+			mut.Lock()
+			defer mut.Unlock()
+			for _, c := range buf[:n] {
+				seen[c]++
+			}
+		}()
+	}
+
+	want := make([]byte, readers*size)
+	for i := range want {
+		want[i] = byte(i)
+	}
+	if _, err := port1.Write(want); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	for _, c := range want {
+		if seen[c] != 1 {
+			t.Fatalf("byte %d read %d times; want once", c, seen[c])
+		}
+	}
+}
+
 func TestDoubleCloseIsNoop(t *testing.T) {
 	portPath, _ := setupLoopbackPorts(t)
 
@@ -310,13 +579,17 @@ func TestBlockingRead(t *testing.T) {
 			t.Log(err)
 			t.Fail()
 		}
-		<-readDone
+		select {
+		case <-readDone:
+		case <-time.After(closeUnblockDuration):
+			t.Fatal("Read() still blocked after Close()")
+		}
 	case <-readDone:
 		t.Fatal("got non-blocking Read(); want blocking Read()")
 	}
 }
 
-func getTestPorts(t *testing.T) (serial.Port, serial.Port) {
+func getTestPorts(t testing.TB) (serial.Port, serial.Port) {
 	portAConnStr, portBConnStr := setupLoopbackPorts(t)
 
 	port1, err := serial.Open(portAConnStr, func(c *serial.Config) {
@@ -329,15 +602,7 @@ func getTestPorts(t *testing.T) (serial.Port, serial.Port) {
 		t.Fatal(err)
 	}
 
-	drain := make([]byte, 1024)
-
-	if err := port1.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
-		t.Fatal(err)
-	}
-	if _, err := port1.Read(drain); err != nil && err != os.ErrDeadlineExceeded {
-		t.Fatal(err)
-	}
-	if err := port1.SetReadDeadline(time.Time{}); err != nil {
+	if err := port1.DrainUntilIdle(50*time.Millisecond, longSleepDuration); err != nil {
 		t.Fatal(err)
 	}
 
@@ -351,17 +616,101 @@ func getTestPorts(t *testing.T) (serial.Port, serial.Port) {
 		t.Fatal(err)
 	}
 
-	if err := port2.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+	if err := port2.DrainUntilIdle(50*time.Millisecond, longSleepDuration); err != nil {
+		t.Fatal(err)
+	}
+
+	return port1, port2
+}
+
+func TestDrainUntilIdle(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	stop := make(chan struct{})
+	chatterDone := make(chan struct{})
+
+	go func() {
+		defer close(chatterDone)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(shortSleepDuration):
+				port1.Write([]byte(testString))
+			}
+		}
+	}()
+
+	if err := port2.DrainUntilIdle(100*time.Millisecond, 300*time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v; want %v while the line is busy", err, os.ErrDeadlineExceeded)
+	}
+
+	close(stop)
+	<-chatterDone
+
+	if err := port2.DrainUntilIdle(100*time.Millisecond, longSleepDuration); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := port2.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	n, err := port2.Read(make([]byte, 1))
+	if !errors.Is(err, os.ErrDeadlineExceeded) || n != 0 {
+		t.Fatalf("read %d bytes, %v after drain; want 0 bytes, %v", n, err, os.ErrDeadlineExceeded)
+	}
+}
+
+func TestLocalEcho(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	var echo bytes.Buffer
+	port1.SetLocalEcho(&echo)
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := port2.Read(drain); err != nil && err != os.ErrDeadlineExceeded {
+
+	port1.SetLocalEcho(nil)
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
 		t.Fatal(err)
 	}
-	if err := port2.SetReadDeadline(time.Time{}); err != nil {
+
+	if echo.String() != testString {
+		t.Fatalf("echoed %q; want %q", echo.String(), testString)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
 		t.Fatal(err)
 	}
 
-	return port1, port2
+	if err := port2.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, len(testString)+1)
+	n, err := port2.Read(buf)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v; want the deadline set before Reopen to fire", err)
+	}
+	if string(buf[:n]) != testString {
+		t.Fatalf("read %q; want %q", buf[:n], testString)
+	}
 }
 
 func TestFullDuplex(t *testing.T) {
@@ -416,3 +765,940 @@ func TestFullDuplex(t *testing.T) {
 
 	time.Sleep(longSleepDuration + time.Second)
 }
+
+func TestSetBreak(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if err := port1.SetBreak(true); err != nil {
+		t.Skipf("break not supported: %v", err)
+	}
+
+	if on, err := port1.Break(); err != nil || !on {
+		t.Fatalf("got %t, %v; want true, nil", on, err)
+	}
+
+	if err := port1.SetBreak(false); err != nil {
+		t.Fatal(err)
+	}
+
+	if on, err := port1.Break(); err != nil || on {
+		t.Fatalf("got %t, %v; want false, nil", on, err)
+	}
+
+	port1.Close()
+
+	if _, err := port1.Break(); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestSendBreak(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	start := time.Now()
+	if err := port1.SendBreak(10 * time.Millisecond); err != nil {
+		t.Skipf("break not supported: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("break lasted %v; want at least 10ms", elapsed)
+	}
+
+	if on, err := port1.Break(); err != nil || on {
+		t.Fatalf("got %t, %v; want false, nil", on, err)
+	}
+
+	port1.Close()
+	if err := port1.SendBreak(0); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestApplyMode(t *testing.T) {
+	portAConnStr, _ := setupLoopbackPorts(t)
+
+	for _, mode := range []serial.ApplyMode{serial.ApplyModeNow, serial.ApplyModeDrain, serial.ApplyModeFlush} {
+		p, err := serial.Open(portAConnStr, func(c *serial.Config) {
+			c.BaudRate = baudRate
+			c.ApplyMode = mode
+		})
+		if err != nil {
+			t.Fatalf("mode %v: %v", mode, err)
+		}
+		p.Close()
+	}
+}
+
+func TestReadUntil(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if _, err := port1.Write([]byte("OK\r\nERROR\r\npartial")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"OK\r\n", "ERROR\r\n"} {
+		got, err := port2.ReadUntil([]byte("\r\n"), 64, longSleepDuration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("read %q; want %q", got, want)
+		}
+	}
+
+	got, err := port2.ReadUntil([]byte("\r\n"), 4, longSleepDuration)
+	if !errors.Is(err, serial.ErrDelimiterNotFound) || string(got) != "part" {
+		t.Fatalf("read %q, %v; want %q, %v", got, err, "part", serial.ErrDelimiterNotFound)
+	}
+
+	got, err = port2.ReadUntil([]byte("\r\n"), 64, 100*time.Millisecond)
+	if !errors.Is(err, os.ErrDeadlineExceeded) || string(got) != "ial" {
+		t.Fatalf("read %q, %v; want %q, %v", got, err, "ial", os.ErrDeadlineExceeded)
+	}
+}
+
+func TestReadUntilByte(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if _, err := port1.Write([]byte("line\npartial")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := port2.ReadUntilByte('\n', 64)
+	if err != nil || string(got) != "line\n" {
+		t.Fatalf("read %q, %v; want %q, nil", got, err, "line\n")
+	}
+
+	if err := port2.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	got, err = port2.ReadUntilByte('\n', 64)
+	if !errors.Is(err, os.ErrDeadlineExceeded) || string(got) != "partial" {
+		t.Fatalf("read %q, %v; want %q, %v", got, err, "partial", os.ErrDeadlineExceeded)
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	go func() {
+		port1.Write([]byte("frame1"))
+		time.Sleep(100 * time.Millisecond)
+		port1.Write([]byte("frame2"))
+	}()
+
+	for _, want := range []string{"frame1", "frame2"} {
+		got, err := port2.ReadFrame(64, 30*time.Millisecond, longSleepDuration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("read %q; want %q", got, want)
+		}
+	}
+
+	got, err := port2.ReadFrame(64, 30*time.Millisecond, 50*time.Millisecond)
+	if !errors.Is(err, os.ErrDeadlineExceeded) || len(got) != 0 {
+		t.Fatalf("read %q, %v; want nothing, %v", got, err, os.ErrDeadlineExceeded)
+	}
+
+	for _, maxSize := range []int{0, -1} {
+		if got, err := port2.ReadFrame(maxSize, 30*time.Millisecond, 0); err == nil {
+			t.Fatalf("ReadFrame(%d) read %q, nil; want an error", maxSize, got)
+		}
+	}
+}
+
+func TestHasInput(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if has, err := port2.HasInput(); err != nil || has {
+		t.Fatalf("got %v, %v; want false, nil", has, err)
+	}
+
+	if _, err := port1.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if has, err := port2.HasInput(); err != nil || !has {
+		t.Fatalf("got %v, %v; want true, nil", has, err)
+	}
+}
+
+func TestInputOutputWaiting(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if n, err := port2.InputWaiting(); err != nil || n != len(testString) {
+		t.Fatalf("got %d, %v; want %d, nil", n, err, len(testString))
+	}
+	if n, err := port1.OutputWaiting(); err != nil || n != 0 {
+		t.Fatalf("got %d, %v; want 0, nil", n, err)
+	}
+
+	port2.Close()
+	if _, err := port2.InputWaiting(); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mut sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *lockedBuffer) Write(b []byte) (int, error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.buf.Write(b)
+}
+
+func (l *lockedBuffer) String() string {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.buf.String()
+}
+
+func TestCopy(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	var dst lockedBuffer
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(&dst, port2)
+		copied <- err
+	}()
+
+	n, err := io.Copy(port1, strings.NewReader(testString))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(testString)) {
+		t.Fatalf("copied %d bytes to the port; want %d", n, len(testString))
+	}
+
+	for start := time.Now(); dst.String() != testString; time.Sleep(shortSleepDuration) {
+		if time.Since(start) > longSleepDuration {
+			t.Fatalf("copied %q from the port; want %q", dst.String(), testString)
+		}
+	}
+
+	port2.Close()
+
+	select {
+	case err := <-copied:
+		if err != nil {
+			t.Fatalf("got %v; want copy ended without error", err)
+		}
+	case <-time.After(longSleepDuration):
+		t.Fatal("copy from the port still running after close")
+	}
+}
+
+func TestTaps(t *testing.T) {
+	portAConnStr, portBConnStr := setupLoopbackPorts(t)
+
+	var readTap, writeTap lockedBuffer
+
+	port1, err := serial.Open(portAConnStr, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.WriteTap = &writeTap
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port1.Close()
+
+	port2, err := serial.Open(portBConnStr, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.ReadTap = &readTap
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port2.Close()
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port2.Read(make([]byte, len(testString))); err != nil {
+		t.Fatal(err)
+	}
+
+	alarm := time.After(longSleepDuration)
+	for readTap.String() != testString || writeTap.String() != testString {
+		select {
+		case <-alarm:
+			t.Fatalf("read tap got %q, write tap got %q; want %q", readTap.String(), writeTap.String(), testString)
+		case <-time.After(shortSleepDuration):
+		}
+	}
+}
+
+func TestSupportsBaudRate(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	for rate, want := range map[int]bool{
+		0:      false,
+		-9600:  false,
+		9600:   true,
+		115200: true,
+	} {
+		if got := port1.SupportsBaudRate(rate); got != want {
+			t.Errorf("%d: got %t; want %t", rate, got, want)
+		}
+	}
+
+	// once closed, only the standard rates are reported
+	port1.Close()
+	for rate, want := range map[int]bool{
+		9600:  true,
+		12345: false,
+	} {
+		if got := port1.SupportsBaudRate(rate); got != want {
+			t.Errorf("closed, %d: got %t; want %t", rate, got, want)
+		}
+	}
+}
+
+func TestResumeTransmission(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if err := port1.ResumeTransmission(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1)
+	if _, err := port2.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != 0x11 {
+		t.Fatalf("read %#x; want XON (0x11)", buf[0])
+	}
+
+	hold, err := port1.XoffHold()
+	switch {
+	case errors.Is(err, serial.ErrUnsupported):
+	case err != nil:
+		t.Fatal(err)
+	case hold:
+		t.Fatal("got XOFF hold after ResumeTransmission")
+	}
+}
+
+func TestUARTType(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	typ, err := port1.UARTType()
+	if err != nil {
+		t.Skipf("UART type not available: %v", err)
+	}
+	if typ == "" {
+		t.Fatal("got empty UART type")
+	}
+	t.Log(typ)
+}
+
+func TestReconfigureDuringRead(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	buf := make([]byte, len(testString))
+	go func() {
+		n, err := io.ReadFull(port2, buf)
+		done <- result{n, err}
+	}()
+	time.Sleep(shortSleepDuration)
+
+	reconfigured := make(chan error, 1)
+	go func() {
+		reconfigured <- port2.Reconfigure(func(c *serial.Config) {
+			c.LowLatency = true
+		})
+	}()
+
+	select {
+	case err := <-reconfigured:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(longSleepDuration):
+		t.Fatal("Reconfigure blocked behind a pending Read")
+	}
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if string(buf) != testString {
+			t.Fatalf("got %q; want %q", buf, testString)
+		}
+	case <-time.After(longSleepDuration):
+		t.Fatal("Read did not continue after Reconfigure")
+	}
+}
+
+func TestUnsupportedBaudRate(t *testing.T) {
+	portAConnStr, _ := setupLoopbackPorts(t)
+
+	for _, rate := range []int{-1, 123, tooFastBaudRate} {
+		p, err := serial.Open(portAConnStr, func(c *serial.Config) {
+			c.BaudRate = rate
+		})
+		if err == nil {
+			conf, _ := p.Config()
+			p.Close()
+			if rate == 123 && conf.BaudRate == rate {
+				// set as a custom rate, e.g. with BOTHER on Linux
+				continue
+			}
+		}
+		if !errors.Is(err, serial.ErrUnsupportedBaudRate) {
+			t.Errorf("%d: got %v; want %v", rate, err, serial.ErrUnsupportedBaudRate)
+		}
+	}
+}
+
+func TestSetReadCancel(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	cancel := make(chan struct{})
+	port2.SetReadCancel(cancel)
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := port2.Read(make([]byte, 1))
+		errc <- err
+	}()
+
+	time.Sleep(shortSleepDuration)
+	close(cancel)
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, serial.ErrReadCanceled) {
+			t.Fatalf("got %v; want %v", err, serial.ErrReadCanceled)
+		}
+	case <-time.After(longSleepDuration):
+		t.Fatal("read still blocked after cancel")
+	}
+
+	port2.SetReadCancel(nil)
+
+	if _, err := port1.Write([]byte{'x'}); err != nil {
+		t.Fatal(err)
+	}
+	if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port2.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("got %v after clearing cancel; want nil", err)
+	}
+}
+
+func TestAddressBit(t *testing.T) {
+	portAConnStr, _ := setupLoopbackPorts(t)
+
+	p, err := serial.Open(portAConnStr, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.AddressBit = true
+	})
+	if errors.Is(err, serial.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+}
+
+func TestWriteRead(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	// stale input WriteRead must discard
+	if _, err := port2.Write([]byte("stale")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(shortSleepDuration)
+
+	go func() {
+		req := make([]byte, 4)
+		if err := port2.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+			return
+		}
+		if _, err := port2.Read(req); err != nil || string(req) != "ping" {
+			return
+		}
+		port2.Write([]byte("pong"))
+	}()
+
+	resp := make([]byte, 4)
+	n, err := port1.WriteRead([]byte("ping"), resp, longSleepDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp[:n]) != "pong" {
+		t.Fatalf("got response %q; want %q", resp[:n], "pong")
+	}
+}
+
+func TestFlowControlDefault(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	fc, err := port1.FlowControl()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc != serial.FlowControlNone {
+		t.Fatalf("got %v; want %v", fc, serial.FlowControlNone)
+	}
+}
+
+func TestFlowControl(t *testing.T) {
+	portPath, _ := setupLoopbackPorts(t)
+
+	for _, want := range []serial.FlowControl{serial.FlowControlNone, serial.FlowControlXONXOFF, serial.FlowControlRTSCTS} {
+		p, err := serial.Open(portPath, func(c *serial.Config) {
+			c.BaudRate = baudRate
+			c.FlowControl = want
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := p.FlowControl()
+		p.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	}
+}
+
+func TestSetDTR(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	t.Run("Set", func(t *testing.T) {
+		for _, on := range []bool{true, false} {
+			err := port1.SetDTR(on)
+			skipIfNoModemLines(t, err)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	port1.Close()
+	if err := port1.SetDTR(true); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestSetRTS(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	t.Run("Set", func(t *testing.T) {
+		for _, on := range []bool{true, false} {
+			err := port1.SetRTS(on)
+			skipIfNoModemLines(t, err)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	port1.Close()
+	if err := port1.SetRTS(true); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := port2.FlushInput(); err != nil {
+		t.Fatal(err)
+	}
+	if has, err := port2.HasInput(); err != nil || has {
+		t.Fatalf("got %v, %v; want no input after FlushInput", has, err)
+	}
+
+	if err := port1.FlushOutput(); err != nil {
+		t.Fatal(err)
+	}
+	if err := port1.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	port1.Close()
+	if err := port1.Flush(); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+	if err := port1.Drain(); err != nil {
+		t.Fatal(err)
+	}
+
+	port1.Close()
+	if err := port1.Drain(); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestBaudRateFallback(t *testing.T) {
+	portAConnStr, _ := setupLoopbackPorts(t)
+
+	p, err := serial.Open(portAConnStr, func(c *serial.Config) {
+		c.BaudRate = tooFastBaudRate
+		c.BaudRateFallback = []int{tooFastBaudRate - 1, baudRate}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	conf, err := p.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.BaudRate != baudRate {
+		t.Fatalf("got baud rate %d; want fallback %d", conf.BaudRate, baudRate)
+	}
+}
+
+func TestRxFIFOTriggerUnsupported(t *testing.T) {
+	portAConnStr, _ := setupLoopbackPorts(t)
+
+	p, err := serial.Open(portAConnStr, func(c *serial.Config) {
+		c.BaudRate = baudRate
+		c.RxFIFOTrigger = 8
+	})
+	if err == nil {
+		p.Close()
+		t.Skip("port supports setting the receive FIFO trigger")
+	}
+	if !errors.Is(err, serial.ErrUnsupported) {
+		t.Fatalf("got %v; want %v on a port without a UART FIFO", err, serial.ErrUnsupported)
+	}
+}
+
+func TestSetReadDeadlineWhileBlocked(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := port2.Read(make([]byte, 1))
+		errc <- err
+	}()
+	time.Sleep(shortSleepDuration)
+
+	const deadline = 50 * time.Millisecond
+	start := time.Now()
+	if err := port2.SetReadDeadline(start.Add(deadline)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errc:
+		if err != os.ErrDeadlineExceeded {
+			t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed < deadline {
+			t.Fatalf("read returned after %v; want at least %v", elapsed, deadline)
+		}
+	case <-time.After(longSleepDuration):
+		t.Fatal("read did not notice a deadline set while it was blocked")
+	}
+}
+
+func TestReadDeadlineGranularity(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	const deadline = 150 * time.Millisecond
+
+	start := time.Now()
+	if err := port2.SetReadDeadline(start.Add(deadline)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port2.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	if elapsed := time.Since(start); elapsed < deadline || elapsed > deadline+50*time.Millisecond {
+		t.Fatalf("deadline fired after %v; want %v-%v", elapsed, deadline, deadline+50*time.Millisecond)
+	}
+}
+
+func TestOpenInvalidConfig(t *testing.T) {
+	for _, cFn := range []func(c *serial.Config){
+		func(c *serial.Config) { c.DataBits = 9 },
+		func(c *serial.Config) { c.DataBits = 4 },
+		func(c *serial.Config) { c.StopBits = serial.StopBits2 + 1 },
+		func(c *serial.Config) { c.Parity = -1 },
+		func(c *serial.Config) { c.BaudRate = -1 },
+		func(c *serial.Config) { c.InputBufferSize = -1 },
+		func(c *serial.Config) { c.InitialDTR = serial.LineLevelHigh + 1 },
+		func(c *serial.Config) { c.XonChar, c.XoffChar = 'x', 'x' },
+		func(c *serial.Config) { c.XoffChar = serial.DefaultXonChar },
+		func(c *serial.Config) { c.XonChar = serial.DefaultXoffChar },
+	} {
+		var conf serial.Config
+		cFn(&conf)
+
+		// the address does not exist, so only validation can fail with
+		// anything but os.ErrNotExist
+		_, err := serial.Open("no-such-port", cFn)
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%+v: got %v; want config rejected", conf, err)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, len(testString)+1)
+	if err := port2.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := port2.Read(buf); err != os.ErrDeadlineExceeded {
+		t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	if s := port1.Stats(); s.Writes != 1 || s.BytesWritten != uint64(len(testString)) {
+		t.Fatalf("got %+v; want 1 write of %d bytes", s, len(testString))
+	}
+	want := serial.Stats{BytesRead: uint64(len(testString)), Reads: 1, ReadTimeouts: 1}
+	if s := port2.Stats(); s != want {
+		t.Fatalf("got %+v; want %+v", s, want)
+	}
+}
+
+func TestLineErrors(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	t.Run("Get", func(t *testing.T) {
+		c, err := port1.LineErrors()
+		skipIfNoErrorCounts(t, err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != (serial.LineErrorCounts{}) {
+			t.Fatalf("got %+v; want no errors", c)
+		}
+	})
+
+	port1.Close()
+	if _, err := port1.LineErrors(); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestModemStatus(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	t.Run("Get", func(t *testing.T) {
+		ms, err := port1.ModemStatus()
+		skipIfNoModemLines(t, err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%+v", ms)
+	})
+
+	port1.Close()
+	if _, err := port1.ModemStatus(); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestWaitModemChange(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// no line changes, so the wait ends with ctx, or at once on ptys, which
+	// have no modem lines
+	start := time.Now()
+	if _, err := port1.WaitModemChange(ctx); err == nil {
+		t.Fatal("got a change; want none")
+	}
+	if elapsed := time.Since(start); elapsed > longSleepDuration {
+		t.Fatalf("wait returned after %v; want it to end with ctx", elapsed)
+	}
+
+	port1.Close()
+	if _, err := port1.WaitModemChange(context.Background()); !errors.Is(err, serial.ErrPortClosed) {
+		t.Fatalf("got %v; want %v", err, serial.ErrPortClosed)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	events, err := port2.Notify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := port1.Write([]byte(testString)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev != serial.EventDataAvailable {
+			t.Fatalf("got event %v; want %v", ev, serial.EventDataAvailable)
+		}
+	case <-time.After(longSleepDuration):
+		t.Fatal("got no event")
+	}
+
+	// the event left the bytes to be read
+	buf := make([]byte, len(testString))
+	if _, err := io.ReadFull(port2, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	port2.Close()
+
+	alarm := time.After(longSleepDuration)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-alarm:
+			t.Fatal("events channel still open after close")
+		}
+	}
+}
+
+func TestModemStatusEvents(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := port1.ModemStatusEvents(ctx)
+	if err != nil {
+		cancel()
+		t.Skipf("modem status not available: %v", err)
+	}
+
+	select {
+	case <-events:
+	case <-time.After(longSleepDuration):
+		t.Fatal("got no initial modem status")
+	}
+
+	cancel()
+
+	alarm := time.After(longSleepDuration)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-alarm:
+			t.Fatal("events channel still open after cancel")
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package serial
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"golang.org/x/sys/windows"
 )
@@ -16,7 +22,10 @@ const (
 	tickResolution = 30 // approx 2x of Windows's default timer resolution
 )
 
-type dcb struct {
+// DCB is the Win32 device control block holding a port's settings. Assert a
+// Port to interface{ DCB() (*serial.DCB, error); SetDCB(*serial.DCB) error }
+// to access it, see (*port).DCB.
+type DCB struct {
 	// https://learn.microsoft.com/en-us/windows/win32/api/winbase/ns-winbase-dcb
 
 	DCBLength uint32
@@ -53,6 +62,47 @@ type dcb struct {
 	WReserved1 uint16
 }
 
+type comstat struct {
+	// https://learn.microsoft.com/en-us/windows/win32/api/winbase/ns-winbase-comstat
+
+	// Flags is a bitfield
+	// DWORD fCtsHold : 1;
+	// DWORD fDsrHold : 1;
+	// DWORD fRlsdHold : 1;
+	// DWORD fXoffHold : 1;
+	// DWORD fXoffSent : 1;
+	// DWORD fEof : 1;
+	// DWORD fTxim : 1;
+	// DWORD fReserved : 25;
+	Flags uint32
+
+	CbInQue  uint32
+	CbOutQue uint32
+}
+
+type commProp struct {
+	// https://learn.microsoft.com/en-us/windows/win32/api/winbase/ns-winbase-commprop
+
+	PacketLength       uint16
+	PacketVersion      uint16
+	ServiceMask        uint32
+	Reserved1          uint32
+	MaxTxQueue         uint32
+	MaxRxQueue         uint32
+	MaxBaud            uint32
+	ProvSubType        uint32
+	ProvCapabilities   uint32
+	SettableParams     uint32
+	SettableBaud       uint32
+	SettableData       uint16
+	SettableStopParity uint16
+	CurrentTxQueue     uint32
+	CurrentRxQueue     uint32
+	ProvSpec1          uint32
+	ProvSpec2          uint32
+	ProvChar           [1]uint16
+}
+
 var baudRates = map[int]uint32{
 	0: cbr19200, // default
 
@@ -89,6 +139,44 @@ const (
 	cbr256000 = 0x3e800
 )
 
+// settableBauds maps baud rates to their bit in COMMPROP's dwSettableBaud,
+// which also encodes dwMaxBaud.
+var settableBauds = map[int]uint32{
+	110:    baud110,
+	300:    baud300,
+	600:    baud600,
+	1200:   baud1200,
+	2400:   baud2400,
+	4800:   baud4800,
+	9600:   baud9600,
+	14400:  baud14400,
+	19200:  baud19200,
+	38400:  baud38400,
+	57600:  baud57600,
+	115200: baud115200,
+	128000: baud128K,
+	// COMMPROP has no bit for 256000 (CBR_256000), drivers that take it
+	// report BAUD_USER
+	256000: baudUser,
+}
+
+const (
+	baud110    = 0x2
+	baud300    = 0x10
+	baud600    = 0x20
+	baud1200   = 0x40
+	baud2400   = 0x100
+	baud4800   = 0x200
+	baud9600   = 0x800
+	baud14400  = 0x1000
+	baud19200  = 0x2000
+	baud38400  = 0x4000
+	baud128K   = 0x10000
+	baud115200 = 0x20000
+	baud57600  = 0x40000
+	baudUser   = 0x10000000
+)
+
 const (
 	dcbfBinary           = 0b01 << 0
 	dcbfParity           = 0b01 << 1
@@ -105,6 +193,25 @@ const (
 	dcbfAbortOnError     = 0b01 << 14
 )
 
+const (
+	comstatfCTSHold  = 0b1 << 0
+	comstatfDSRHold  = 0b1 << 1
+	comstatfRLSDHold = 0b1 << 2
+	comstatfXoffHold = 0b1 << 3
+	comstatfXoffSent = 0b1 << 4
+	comstatfEOF      = 0b1 << 5
+	comstatfTxim     = 0b1 << 6
+)
+
+const (
+	ceRxOver   = 0x1
+	ceOverrun  = 0x2
+	ceRxParity = 0x4
+	ceFrame    = 0x8
+	ceBreak    = 0x10
+	ceTxFull   = 0x100
+)
+
 const (
 	dtrControlDisable   = 0x0
 	dtrControlEnable    = 0x1
@@ -118,6 +225,41 @@ const (
 	rtsControlToggle    = 0x3
 )
 
+const (
+	evRxChar = 0x1
+	evCTS    = 0x8
+	evDSR    = 0x10
+	evRLSD   = 0x20
+	evBreak  = 0x40
+	evErr    = 0x80
+	evRing   = 0x100
+)
+
+const (
+	msCTSOn  = 0x10
+	msDSROn  = 0x20
+	msRingOn = 0x40
+	msRLSDOn = 0x80
+)
+
+const (
+	purgeTxAbort = 0x1
+	purgeRxAbort = 0x2
+	purgeTxClear = 0x4
+	purgeRxClear = 0x8
+)
+
+const (
+	setXON = 0x2
+	setRTS = 0x3
+	clrRTS = 0x4
+	setDTR = 0x5
+	clrDTR = 0x6
+
+	setBreak = 0x8
+	clrBreak = 0x9
+)
+
 const (
 	oneStopBit  = 0x0
 	twoStopBits = 0x2
@@ -129,10 +271,88 @@ const (
 	noParity   = 0x0
 )
 
+// CommStatus is the communications status reported by ClearCommError. It is
+// only available on Windows; assert a Port to
+// interface{ CommStatus() (serial.CommStatus, error) } to retrieve it.
+type CommStatus struct {
+	CTSHold  bool // transmission waiting for CTS
+	DSRHold  bool // transmission waiting for DSR
+	RLSDHold bool // transmission waiting for RLSD (DCD)
+	XoffHold bool // transmission waiting because XOFF was received
+	XoffSent bool // transmission waiting because XOFF was sent
+	EOF      bool // EOF character received
+	Txim     bool // character queued by TransmitCommChar
+
+	InQueue  int // bytes received but not yet read
+	OutQueue int // bytes written but not yet transmitted
+
+	Errors CommErrors
+}
+
+// CommErrors are the error flags reported, and cleared, by ClearCommError. Assert
+// a Port to interface{ ClearErrors() (serial.CommErrors, error) } to clear them
+// on their own.
+type CommErrors struct {
+	Break    bool // break condition detected
+	Frame    bool // framing error detected
+	Overrun  bool // character buffer overrun, next character lost
+	RxOver   bool // input buffer overflow
+	RxParity bool // parity error detected
+	TxFull   bool // output buffer full
+}
+
 type port struct {
+	path string
+	conf Config
+
 	handle windows.Handle
 
-	ro, wo           *windows.Overlapped
+	// mut guards handle: I/O holds it for reading, so Close, which holds it
+	// for writing, cannot invalidate the handle under it
+	mut sync.RWMutex
+
+	closing    bool
+	draining   bool // a Drain is waiting in FlushFileBuffers
+	closingMut sync.Mutex
+
+	origDCB DCB // settings before open, restored on close
+
+	readMut, writeMut sync.Mutex
+
+	// reconfiguring is set while reconfigure, holding reconfigMut, waits for
+	// and holds mut, see yield
+	reconfiguring atomic.Bool
+	reconfigMut   sync.Mutex
+
+	echo    io.Writer
+	echoMut sync.Mutex
+
+	readCancel    <-chan struct{}
+	readCancelMut sync.Mutex
+
+	// contexts of ReadContext and WriteContext, guarded by readMut and writeMut
+	readCtx, writeCtx context.Context
+
+	readTap, writeTap *tap
+
+	stats stats
+
+	breakOn  bool
+	breakMut sync.Mutex
+
+	// communications errors cleared while querying the port, kept until
+	// CommStatus, ClearErrors or LineErrors reports them
+	commErrs    uint32
+	overrun     bool // an overrun cleared that StrictOverrun has yet to report
+	commErrsMut sync.Mutex
+
+	// ro is guarded by readMut and wo by writeMut, so concurrent reads cannot
+	// share ro while a read and a write can still overlap
+	ro, wo *windows.Overlapped
+
+	readTimeout, writeTimeout uint32 // COMMTIMEOUTS in effect, in milliseconds
+	timeoutsMut               sync.Mutex
+
 	readDeadline     time.Time
 	readDeadlineMut  sync.Mutex
 	writeDeadline    time.Time
@@ -140,14 +360,64 @@ type port struct {
 }
 
 func nativeOpen(path string, conf *Config) (*port, error) {
+	p := &port{
+		path: path, conf: *conf,
+		readTap: newTap(conf.ReadTap), writeTap: newTap(conf.WriteTap),
+	}
+
+	// open closes the port's handle itself if it fails, leaving the events
+	// of the overlapped structures to close here
+	opened := false
+	defer func() {
+		if opened {
+			return
+		}
+		for _, o := range []*windows.Overlapped{p.ro, p.wo} {
+			if o != nil {
+				windows.CloseHandle(o.HEvent)
+			}
+		}
+	}()
+
+	var err error
+	if p.ro, err = newOverlapped(); err != nil {
+		return nil, err
+	}
+	if p.wo, err = newOverlapped(); err != nil {
+		return nil, err
+	}
+	if err := p.open(); err != nil {
+		return nil, err
+	}
+
+	opened = true
+	return p, nil
+}
+
+// validateNative rejects settings Windows does not support.
+func (c *Config) validateNative() error {
+	if c.AddressBit {
+		return fmt.Errorf("address bit mode: %w", ErrUnsupported)
+	}
+	if c.ReportBreaks {
+		return fmt.Errorf("break reporting: %w", ErrUnsupported)
+	}
+	if c.RS485.Enabled {
+		return fmt.Errorf("RS-485 mode: %w", ErrUnsupported)
+	}
+	return nil
+}
+
+// open opens p.path and configures it according to p.conf.
+func (p *port) open() (err error) {
 	// required when using CreateFile to get a handle to a device
 	// https://learn.microsoft.com/en-us/windows/win32/devio/communications-resource-handles
 	const pathPrefix = `\\.\`
 
-	path = pathPrefix + path
+	conf := &p.conf
 
 	handle, err := windows.CreateFile(
-		windows.StringToUTF16Ptr(path),
+		windows.StringToUTF16Ptr(pathPrefix+p.path),
 		windows.GENERIC_READ|windows.GENERIC_WRITE,
 		0,                            //exclusive access
 		nil,                          // default security attributes
@@ -157,138 +427,442 @@ func nativeOpen(path string, conf *Config) (*port, error) {
 	)
 	if err != nil {
 		switch err {
-		case windows.ERROR_ACCESS_DENIED:
-			return nil, ErrPortInUse
+		case windows.ERROR_ACCESS_DENIED, windows.ERROR_SHARING_VIOLATION:
+			// comm ports are opened exclusively, so access is denied while
+			// another handle is open
+			return &inUseError{err}
 		}
-		return nil, err
+		return err
 	}
+	defer func() {
+		if err != nil {
+			windows.CloseHandle(handle)
+		}
+	}()
 
-	var d dcb
+	var d DCB
 
 	if err := getCommState(handle, &d); err != nil {
-		return nil, err
+		return err
 	}
 
-	dcbInit(&d)
-	if err := dcbSetBaudRate(&d, conf.BaudRate); err != nil {
-		return nil, err
-	}
-	if err := dcbSetByteSize(&d, conf.DataBits); err != nil {
-		return nil, err
-	}
-	if err := dcbSetStopBits(&d, conf.StopBits); err != nil {
-		return nil, err
+	origDCB := d
+
+	// lines left to the default are deasserted, by the DCB alone
+	dtr, rts := conf.initialLines(LineLevelLow)
+	if err := p.configure(handle, &d, dtr, rts); err != nil {
+		return err
 	}
-	if err := dcbSetParity(&d, conf.Parity); err != nil {
-		return nil, err
+
+	dtr, rts = conf.initialLines(LineLevelNil)
+	if err := escapeLineLevels(handle, dtr, rts, conf.FlowControl); err != nil {
+		return err
 	}
 
-	if err := setCommState(handle, &d); err != nil {
-		return nil, err
+	if err := setCommTimeouts(handle, tickResolution, tickResolution); err != nil {
+		return err
 	}
 
-	var ct windows.CommTimeouts
+	p.handle = handle
+	p.origDCB = origDCB
+	p.readTimeout, p.writeTimeout = tickResolution, tickResolution
 
-	if err := windows.GetCommTimeouts(handle, &ct); err != nil {
-		return nil, err
+	return nil
+}
+
+// configure applies p.conf to handle, starting from its current settings in d,
+// with DTR and RTS put at dtr and rts, LineLevelNil leaving a line as it is.
+func (p *port) configure(handle windows.Handle, d *DCB, dtr, rts LineLevel) error {
+	conf := &p.conf
+	origFlags := d.Flags
+
+	dcbInit(d)
+	dcbSetLineLevels(d, origFlags, dtr, rts)
+	if err := dcbSetFlowControl(d, conf.FlowControl, conf.XonChar, conf.XoffChar); err != nil {
+		return err
+	}
+	dcbSetFlowControlLimits(d, conf.XonLimit, conf.XoffLimit)
+	if conf.AbortOnError {
+		d.Flags |= dcbfAbortOnError
+	} else {
+		d.Flags &^= dcbfAbortOnError
+	}
+	if conf.HonorModemControl {
+		d.Flags |= dcbfDSRSensitivity
+	} else {
+		d.Flags &^= dcbfDSRSensitivity
+	}
+	if err := dcbSetByteSize(d, conf.DataBits); err != nil {
+		return err
+	}
+	if err := dcbSetStopBits(d, conf.StopBits); err != nil {
+		return err
+	}
+	if err := dcbSetParity(d, conf.Parity); err != nil {
+		return err
+	}
+	if conf.DisableParityCheck {
+		d.Flags &^= dcbfParity // generate parity, but don't check it
 	}
 
-	ct.ReadIntervalTimeout = 0
-	ct.ReadTotalTimeoutMultiplier = 0
-	ct.ReadTotalTimeoutConstant = tickResolution
-	ct.WriteTotalTimeoutMultiplier = 0
-	ct.WriteTotalTimeoutConstant = tickResolution
+	if err := p.setBaudRate(handle, d); err != nil {
+		return err
+	}
 
-	if err := windows.SetCommTimeouts(handle, &ct); err != nil {
-		return nil, err
+	if err := setBufferSizes(handle, conf.InputBufferSize, conf.OutputBufferSize); err != nil {
+		return fmt.Errorf("error setting buffer sizes: %w", err)
 	}
 
-	ro, err := newOverlapped()
-	if err != nil {
-		return nil, err
+	if conf.StrictOverrun {
+		// discard overruns that happened before the port was configured
+		if err := clearCommError(handle, nil, nil); err != nil {
+			return err
+		}
 	}
 
-	wo, err := newOverlapped()
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// setBufferSizes requests driver buffers of in and out bytes. SetupComm sets
+// both, so a size of zero is replaced by the current size.
+func setBufferSizes(handle windows.Handle, in, out int) error {
+	if in == 0 && out == 0 {
+		return nil
 	}
 
-	return &port{
-		ro: ro, wo: wo,
-		handle: handle,
-	}, nil
+	if in == 0 || out == 0 {
+		var prop commProp
+		if err := getCommProperties(handle, &prop); err != nil {
+			return err
+		}
+		if in == 0 {
+			in = int(prop.CurrentRxQueue)
+		}
+		if out == 0 {
+			out = int(prop.CurrentTxQueue)
+		}
+	}
+
+	return setupComm(handle, uint32(in), uint32(out))
 }
 
-func (p *port) Read(b []byte) (int, error) {
+// read is Read, returning once at least atLeast bytes are read rather than
+// once b is full, with an additional deadline, ignored if zero, that is
+// honored alongside the deadline set by SetReadDeadline.
+func (p *port) read(b []byte, atLeast int, deadline time.Time) (int, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
 	var read uint32
 
 	for {
-		if p.handle == windows.InvalidHandle {
+		p.yield()
+
+		if p.isClosing() || p.handle == windows.InvalidHandle {
 			return int(read), ErrPortClosed
 		}
-		if p.readDeadlineExpired() {
+		if p.readDeadlineExpired() || deadlineExpired(deadline) {
 			return int(read), os.ErrDeadlineExceeded
 		}
+		if p.readCanceled() {
+			return int(read), ErrReadCanceled
+		}
+		if err := ctxErr(p.readCtx); err != nil {
+			return int(read), err
+		}
+		if err := p.setIOTimeout(&p.readTimeout, earliest(p.readDeadlineTime(), deadline)); err != nil {
+			return int(read), err
+		}
+		if err := p.checkOverrun(); err != nil {
+			return int(read), err
+		}
+
+		end := len(b)
+		if atLeast < len(b) {
+			// ask for only what is needed, plus whatever is already queued,
+			// so ReadFile need not wait for b to fill
+			queued, _, err := p.queues()
+			if err == nil {
+				err = p.overrunErr()
+			}
+			if err != nil {
+				return int(read), err
+			}
+			end = int(read) + queued
+			if end < atLeast {
+				end = atLeast
+			}
+			if end > len(b) {
+				end = len(b)
+			}
+		}
 
 		var nul uint32
-		if err := windows.ReadFile(p.handle, b[read:], &nul, p.ro); err != nil {
+		if err := sysReadFile(p.handle, b[read:end], &nul, p.ro); err != nil {
 			switch err {
 			case windows.ERROR_OPERATION_ABORTED:
-				return int(read), ErrPortClosed
+				return int(read), p.readAbortedErr()
 			case windows.ERROR_IO_PENDING:
 				// not an error, proceed to wait for completion
 			default:
-				return int(read), err
+				return int(read), disconnectErr(err)
 			}
 		}
 
 		var done uint32
-		if err := windows.GetOverlappedResult(p.handle, p.ro, &done, true); err != nil {
+		stop := p.watchRead()
+		err := windows.GetOverlappedResult(p.handle, p.ro, &done, true)
+		stop()
+		if err != nil {
 			switch err {
 			case windows.ERROR_OPERATION_ABORTED:
-				return int(read), ErrPortClosed
+				return int(read + done), p.readAbortedErr()
 			}
-			return int(read + done), err
+			return int(read + done), disconnectErr(err)
 		}
 
 		read += done
 
-		if int(read) == len(b) {
-			return int(read), nil
+		if done == 0 && !p.conf.AbortOnError {
+			// nothing arrived before the timeout; clear any error the
+			// driver may be holding reads back on
+			if _, _, err := p.queues(); err != nil {
+				return int(read), err
+			}
+			if err := p.overrunErr(); err != nil {
+				return int(read), err
+			}
+		}
+
+		if int(read) >= atLeast {
+			return int(read), p.checkOverrun()
+		}
+	}
+}
+
+// tryRead is read, reading only bytes already received and returning at once
+// if there are none. ReadFile is only asked for bytes in the input queue, so
+// it completes without waiting, whatever the COMMTIMEOUTS.
+func (p *port) tryRead(b []byte) (int, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return 0, ErrPortClosed
+	}
+
+	queued, _, err := p.queues()
+	if err == nil {
+		err = p.overrunErr()
+	}
+	if err != nil || queued == 0 || len(b) == 0 {
+		return 0, err
+	}
+	if queued < len(b) {
+		b = b[:queued]
+	}
+
+	var nul uint32
+	if err := sysReadFile(p.handle, b, &nul, p.ro); err != nil {
+		switch err {
+		case windows.ERROR_OPERATION_ABORTED:
+			return 0, p.abortedErr()
+		case windows.ERROR_IO_PENDING:
+			// not an error, proceed to wait for completion
+		default:
+			return 0, disconnectErr(err)
+		}
+	}
+
+	var done uint32
+	if err := windows.GetOverlappedResult(p.handle, p.ro, &done, true); err != nil {
+		switch err {
+		case windows.ERROR_OPERATION_ABORTED:
+			return int(done), p.abortedErr()
+		}
+		return int(done), disconnectErr(err)
+	}
+	return int(done), nil
+}
+
+// queues returns the number of bytes received but not yet read, and written
+// but not yet transmitted. Querying the queues clears pending communications
+// errors, which are kept for whatever reports them next.
+func (p *port) queues() (in, out int, err error) {
+	var cs comstat
+	if _, err := p.clearCommError(&cs); err != nil {
+		return 0, 0, err
+	}
+	return int(cs.CbInQue), int(cs.CbOutQue), nil
+}
+
+// clearCommError clears the driver's pending communications errors, so reads
+// it holds back on can proceed, and returns them. They are kept for
+// takeCommErrors, as clearing them here must not hide them from CommStatus,
+// ClearErrors and LineErrors.
+func (p *port) clearCommError(cs *comstat) (uint32, error) {
+	var errs uint32
+	if err := clearCommError(p.handle, &errs, cs); err != nil {
+		return 0, err
+	}
+
+	p.commErrsMut.Lock()
+	p.commErrs |= errs
+	if p.conf.StrictOverrun && errs&(ceOverrun|ceRxOver) != 0 {
+		p.overrun = true
+	}
+	p.commErrsMut.Unlock()
+
+	return errs, nil
+}
+
+// takeCommErrors clears the driver's pending communications errors and
+// returns them along with those cleared since it was last called.
+func (p *port) takeCommErrors(cs *comstat) (uint32, error) {
+	if _, err := p.clearCommError(cs); err != nil {
+		return 0, err
+	}
+
+	p.commErrsMut.Lock()
+	defer p.commErrsMut.Unlock()
+
+	errs := p.commErrs
+	p.commErrs = 0
+	return errs, nil
+}
+
+// abortedErr returns the error for an I/O operation that was aborted, either
+// by Close or, with AbortOnError, by a communications error.
+func (p *port) abortedErr() error {
+	if p.conf.AbortOnError && !p.isClosing() && p.handle != windows.InvalidHandle {
+		return ErrCommAborted
+	}
+	return ErrPortClosed
+}
+
+// readAbortedErr is abortedErr for a read, which SetReadDeadline also aborts
+// once the deadline has passed, as does watchRead once the read is canceled or
+// its context is done.
+func (p *port) readAbortedErr() error {
+	if !p.isClosing() {
+		switch {
+		case p.readDeadlineExpired():
+			return os.ErrDeadlineExceeded
+		case p.readCanceled():
+			return ErrReadCanceled
 		}
+		if err := ctxErr(p.readCtx); err != nil {
+			return err
+		}
+	}
+	return p.abortedErr()
+}
+
+// writeAbortedErr is abortedErr for a write, which watchWrite also aborts once
+// its context is done.
+func (p *port) writeAbortedErr() error {
+	if !p.isClosing() {
+		if err := ctxErr(p.writeCtx); err != nil {
+			return err
+		}
+	}
+	return p.abortedErr()
+}
+
+// watchRead cancels the pending ReadFile once the read's cancel channel or
+// context is done, until stop is called. It must be called with p.mut read
+// locked, which must be held until stop returns.
+func (p *port) watchRead() (stop func()) {
+	cancel := func() { windows.CancelIoEx(p.handle, p.ro) }
+	stopCancel := watch(p.readCancelChan(), cancel)
+	stopCtx := watch(ctxDone(p.readCtx), cancel)
+	return func() {
+		stopCancel()
+		stopCtx()
 	}
 }
 
-func (p *port) Write(b []byte) (int, error) {
+// watchWrite cancels the pending WriteFile once the write's context is done,
+// until stop is called. It must be called with p.mut read locked, which must be
+// held until stop returns.
+func (p *port) watchWrite() (stop func()) {
+	return watch(ctxDone(p.writeCtx), func() { windows.CancelIoEx(p.handle, p.wo) })
+}
+
+// checkOverrun returns ErrOverrun if the driver has reported an overrun since
+// it was last called. It only checks if the port is configured with
+// StrictOverrun.
+func (p *port) checkOverrun() error {
+	if !p.conf.StrictOverrun {
+		return nil
+	}
+
+	if _, err := p.clearCommError(nil); err != nil {
+		return err
+	}
+	return p.overrunErr()
+}
+
+// overrunErr returns ErrOverrun if an overrun has been cleared since it was
+// last reported, with StrictOverrun.
+func (p *port) overrunErr() error {
+	p.commErrsMut.Lock()
+	defer p.commErrsMut.Unlock()
+
+	if !p.overrun {
+		return nil
+	}
+	p.overrun = false
+	return ErrOverrun
+}
+
+// write is Write with an additional deadline, ignored if zero, that is honored
+// alongside the deadline set by SetWriteDeadline.
+func (p *port) write(b []byte, deadline time.Time) (int, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
 	var written uint32
 
 	for {
-		if p.handle == windows.InvalidHandle {
+		p.yield()
+
+		if p.isClosing() || p.handle == windows.InvalidHandle {
 			return int(written), ErrPortClosed
 		}
-		if p.writeDeadlineExpired() {
+		if p.writeDeadlineExpired() || deadlineExpired(deadline) {
 			return int(written), os.ErrDeadlineExceeded
 		}
+		if err := ctxErr(p.writeCtx); err != nil {
+			return int(written), err
+		}
+		if err := p.setIOTimeout(&p.writeTimeout, earliest(p.writeDeadlineTime(), deadline)); err != nil {
+			return int(written), err
+		}
 
 		var nul uint32
-		if err := windows.WriteFile(p.handle, b, &nul, p.wo); err != nil {
+		if err := sysWriteFile(p.handle, b[written:], &nul, p.wo); err != nil {
 			switch err {
 			case windows.ERROR_OPERATION_ABORTED:
-				return int(written), ErrPortClosed
+				return int(written), p.abortedErr()
 			case windows.ERROR_IO_PENDING:
 			// not an error, proceed to wait for completion
 			default:
-				return int(written), err
+				return int(written), writeFailed(int(written), disconnectErr(err))
 			}
 		}
 
 		var done uint32
-		if err := windows.GetOverlappedResult(p.handle, p.wo, &done, true); err != nil {
+		stop := p.watchWrite()
+		err := windows.GetOverlappedResult(p.handle, p.wo, &done, true)
+		stop()
+		if err != nil {
+			written += done
 			switch err {
 			case windows.ERROR_OPERATION_ABORTED:
-				return int(written + done), ErrPortClosed
+				return int(written), p.writeAbortedErr()
 			}
-			return int(written + done), err
+			return int(written), writeFailed(int(written), disconnectErr(err))
 		}
 
 		written += done
@@ -299,12 +873,35 @@ func (p *port) Write(b []byte) (int, error) {
 	}
 }
 
-func (p *port) Close() error {
-	if p.handle == windows.InvalidHandle {
+func (p *port) close() error {
+	if p.isClosed() {
 		return nil
 	}
 
+	p.closingMut.Lock()
+	p.closing = true
+	draining := p.draining
+	p.closingMut.Unlock()
+
+	// abort pending I/O, so it sees the port closing and releases the lock
 	cancelErr := windows.CancelIoEx(p.handle, nil)
+	if draining {
+		// end a pending Drain by discarding what it waits to transmit
+		purgeComm(p.handle, purgeTxAbort|purgeTxClear)
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.handle == windows.InvalidHandle {
+		// closed concurrently
+		return nil
+	}
+
+	if !p.conf.KeepSettingsOnClose {
+		// best effort, the port is closed regardless
+		setCommState(p.handle, &p.origDCB)
+	}
 
 	if err := windows.CloseHandle(p.handle); err != nil {
 		return err
@@ -318,48 +915,700 @@ func (p *port) Close() error {
 	return nil
 }
 
-func (p *port) SetReadDeadline(t time.Time) error {
-	p.readDeadlineMut.Lock()
-	defer p.readDeadlineMut.Unlock()
+func (p *port) isClosed() bool {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	return p.isClosing() || p.handle == windows.InvalidHandle
+}
 
-	p.readDeadline = t
-	return nil
+func (p *port) isClosing() bool {
+	p.closingMut.Lock()
+	defer p.closingMut.Unlock()
+	return p.closing
 }
 
-func (p *port) readDeadlineExpired() bool {
-	p.readDeadlineMut.Lock()
-	defer p.readDeadlineMut.Unlock()
+// supportsBaudRate checks baudRate against the standard rates and, if the
+// port is open, against the rates the driver reports as settable. Rates other
+// than the standard ones need the driver to report BAUD_USER.
+func (p *port) supportsBaudRate(baudRate int) bool {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
 
-	return !p.readDeadline.IsZero() && time.Now().After(p.readDeadline)
-}
+	_, standard := baudRates[baudRate]
 
-func (p *port) SetWriteDeadline(t time.Time) error {
-	p.writeDeadlienMut.Lock()
-	defer p.writeDeadlienMut.Unlock()
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return standard
+	}
 
-	p.writeDeadline = t
-	return nil
+	var prop commProp
+	if err := getCommProperties(p.handle, &prop); err != nil {
+		return true // driver doesn't say, let SetCommState decide
+	}
+	if max := maxBaudRate(prop.MaxBaud); max > 0 && baudRate > max {
+		return false
+	}
+	if prop.SettableBaud&baudUser != 0 {
+		return true // any rate up to dwMaxBaud, custom ones included
+	}
+	return standard && prop.SettableBaud&settableBauds[baudRate] != 0
 }
 
-func (p *port) writeDeadlineExpired() bool {
-	p.writeDeadlienMut.Lock()
-	defer p.writeDeadlienMut.Unlock()
-
-	return !p.writeDeadline.IsZero() && time.Now().After(p.writeDeadline)
+// maxBaudRate returns the rate COMMPROP's dwMaxBaud stands for, or 0 if it
+// sets no limit, i.e. is BAUD_USER or unknown.
+func maxBaudRate(maxBaud uint32) int {
+	if maxBaud == baudUser {
+		return 0
+	}
+	for rate, bit := range settableBauds {
+		if bit == maxBaud {
+			return rate
+		}
+	}
+	return 0
 }
 
-func dcbInit(d *dcb) {
-	d.Flags |= dcbfBinary // enable binary mode
+// flush discards bytes received but not yet read if input is set, and bytes
+// written but not yet transmitted if output is set.
+func (p *port) flush(input, output bool) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
 
-	// disable hardware flow control
-	d.Flags &^= dcbfOutxCTSFlow
-	d.Flags &^= dcbfOutxDSRFlow
-	d.Flags &^= dcbfDTRControl
-	d.Flags &^= dcbfRTSControl
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return ErrPortClosed
+	}
 
-	// disable software flow control
-	d.Flags &^= dcbfOutX
-	d.Flags &^= dcbfInX
+	var flags uint32
+	if input {
+		flags |= purgeRxClear
+	}
+	if output {
+		flags |= purgeTxClear
+	}
+	if flags == 0 {
+		return nil
+	}
+	return purgeComm(p.handle, flags)
+}
+
+// drain blocks until bytes written have been transmitted. FlushFileBuffers
+// has no timeout, so Close purges the output it is waiting on to end it.
+func (p *port) drain() error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.handle == windows.InvalidHandle {
+		return ErrPortClosed
+	}
+
+	p.closingMut.Lock()
+	if p.closing {
+		p.closingMut.Unlock()
+		return ErrPortClosed
+	}
+	p.draining = true
+	p.closingMut.Unlock()
+
+	err := windows.FlushFileBuffers(p.handle)
+
+	p.closingMut.Lock()
+	p.draining = false
+	closing := p.closing
+	p.closingMut.Unlock()
+
+	if closing {
+		return ErrPortClosed
+	}
+	return err
+}
+
+func (p *port) setBreak(on bool) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return ErrPortClosed
+	}
+
+	function := uint32(clrBreak)
+	if on {
+		function = setBreak
+	}
+	return escapeCommFunction(p.handle, function)
+}
+
+// SetDTR asserts or deasserts DTR.
+func (p *port) SetDTR(on bool) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return ErrPortClosed
+	}
+
+	function := uint32(clrDTR)
+	if on {
+		function = setDTR
+	}
+	return escapeCommFunction(p.handle, function)
+}
+
+// SetRTS asserts or deasserts RTS, e.g. to drive the direction of an RS-485
+// transceiver. Drivers may refuse under RTS/CTS flow control, where they
+// drive RTS themselves.
+func (p *port) SetRTS(on bool) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return ErrPortClosed
+	}
+
+	function := uint32(clrRTS)
+	if on {
+		function = setRTS
+	}
+	return escapeCommFunction(p.handle, function)
+}
+
+// reopen opens the port again after it has been closed.
+func (p *port) reopen() error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if err := p.open(); err != nil {
+		return err
+	}
+
+	p.closingMut.Lock()
+	p.closing = false
+	p.closingMut.Unlock()
+
+	return nil
+}
+
+// reconfigure applies conf to the open port, keeping the previous
+// configuration if it cannot be applied. DTR and RTS are left alone.
+func (p *port) reconfigure(conf *Config) error {
+	p.reconfigMut.Lock()
+	defer p.reconfigMut.Unlock()
+	p.reconfiguring.Store(true)
+	defer p.reconfiguring.Store(false)
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return ErrPortClosed
+	}
+
+	var saved DCB
+	if err := getCommState(p.handle, &saved); err != nil {
+		return err
+	}
+
+	prev := p.conf
+	p.conf = *conf
+	d := saved
+	if err := p.configure(p.handle, &d, LineLevelNil, LineLevelNil); err != nil {
+		// configure may have failed after applying the DCB, best effort
+		setCommState(p.handle, &saved)
+		p.conf = prev
+		return err
+	}
+	return nil
+}
+
+// wakeRead would make a pending read recheck its state, but reads already do
+// so every tickResolution, as ReadFile times out.
+func (p *port) wakeRead() {}
+
+// SetReadDeadline sets the read deadline. A deadline already past also
+// cancels a pending ReadFile, so a blocked read returns at once rather than
+// once its COMMTIMEOUTS expire, as on Linux.
+func (p *port) SetReadDeadline(t time.Time) error {
+	p.readDeadlineMut.Lock()
+	p.readDeadline = t
+	p.readDeadlineMut.Unlock()
+
+	if t.IsZero() || time.Now().Before(t) {
+		return nil
+	}
+
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return nil
+	}
+	// ERROR_NOT_FOUND if no read is pending, in which case the next read
+	// checks the deadline before it starts
+	windows.CancelIoEx(p.handle, p.ro)
+	return nil
+}
+
+func (p *port) readDeadlineTime() time.Time {
+	p.readDeadlineMut.Lock()
+	defer p.readDeadlineMut.Unlock()
+
+	return p.readDeadline
+}
+
+func (p *port) readDeadlineExpired() bool {
+	p.readDeadlineMut.Lock()
+	defer p.readDeadlineMut.Unlock()
+
+	return deadlineExpired(p.readDeadline)
+}
+
+func (p *port) SetWriteDeadline(t time.Time) error {
+	p.writeDeadlienMut.Lock()
+	defer p.writeDeadlienMut.Unlock()
+
+	p.writeDeadline = t
+	return nil
+}
+
+func (p *port) writeDeadlineTime() time.Time {
+	p.writeDeadlienMut.Lock()
+	defer p.writeDeadlienMut.Unlock()
+
+	return p.writeDeadline
+}
+
+func (p *port) writeDeadlineExpired() bool {
+	p.writeDeadlienMut.Lock()
+	defer p.writeDeadlienMut.Unlock()
+
+	return deadlineExpired(p.writeDeadline)
+}
+
+// ResumeTransmission recovers from a lost XON under software flow control by
+// resuming output as if XON had been received, and sending XON in case the
+// other end is waiting on one too.
+func (p *port) ResumeTransmission() error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return ErrPortClosed
+	}
+
+	if err := escapeCommFunction(p.handle, setXON); err != nil {
+		return err
+	}
+
+	var d DCB
+	if err := getCommState(p.handle, &d); err != nil {
+		return err
+	}
+
+	return transmitCommChar(p.handle, orDefault(byte(d.XonChar), DefaultXonChar))
+}
+
+// XoffHold reports whether output is held because XOFF was received. Like
+// CommStatus, it clears any pending communications errors.
+func (p *port) XoffHold() (bool, error) {
+	cs, err := p.CommStatus()
+	return cs.XoffHold, err
+}
+
+// FlowControl reads back the flow control mode in effect from the DCB. If
+// both hardware and software flow control are enabled, FlowControlRTSCTS is
+// reported.
+func (p *port) FlowControl() (FlowControl, error) {
+	d, err := p.DCB()
+	if err != nil {
+		return FlowControlNil, err
+	}
+	return dcbFlowControl(d), nil
+}
+
+func dcbFlowControl(d *DCB) FlowControl {
+	switch {
+	case d.Flags&dcbfOutxCTSFlow != 0,
+		d.Flags&dcbfRTSControl == rtsControlHandshake<<12:
+		return FlowControlRTSCTS
+	case d.Flags&(dcbfOutX|dcbfInX) != 0:
+		return FlowControlXONXOFF
+	default:
+		return FlowControlNone
+	}
+}
+
+// CurrentConfig returns the port's configuration with the baud rate,
+// character size, parity, stop bits and flow control read back from the
+// driver, e.g. to confirm that defaults took effect. Settings the package
+// cannot express, such as mark parity, are as configured, as are the
+// remaining settings.
+func (p *port) CurrentConfig() (Config, error) {
+	d, err := p.DCB()
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf := p.conf
+
+	conf.BaudRate = int(d.BaudRate)
+	conf.DataBits = int(d.ByteSize)
+	switch d.Parity {
+	case noParity:
+		conf.Parity = ParityNone
+	case oddParity:
+		conf.Parity = ParityOdd
+	case evenParity:
+		conf.Parity = ParityEven
+	}
+	switch d.StopBits {
+	case oneStopBit:
+		conf.StopBits = StopBits1
+	case twoStopBits:
+		conf.StopBits = StopBits2
+	}
+	conf.FlowControl = dcbFlowControl(d)
+	conf.XonChar, conf.XoffChar = byte(d.XonChar), byte(d.XoffChar)
+	conf.HonorModemControl = d.Flags&dcbfDSRSensitivity != 0
+
+	return conf, nil
+}
+
+// setBaudRate applies d with conf.BaudRate or, if the platform or driver
+// does not support it, the first supported rate in conf.BaudRateFallback,
+// which then replaces conf.BaudRate.
+func (p *port) setBaudRate(handle windows.Handle, d *DCB) error {
+	conf := &p.conf
+
+	var firstErr error
+	for _, rate := range append([]int{conf.BaudRate}, conf.BaudRateFallback...) {
+		err := dcbSetBaudRate(d, rate)
+		if err == nil {
+			err = setCommState(handle, d)
+			if err == windows.ERROR_INVALID_PARAMETER {
+				// the driver rejected the rate
+				err = fmt.Errorf("%w: %d", ErrUnsupportedBaudRate, rate)
+			}
+		}
+		if errors.Is(err, ErrUnsupportedBaudRate) {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if rate != conf.BaudRate {
+			conf.logf("serial: %s does not support %d baud, falling back to %d", p.path, conf.BaudRate, rate)
+			conf.BaudRate = rate
+		}
+		return nil
+	}
+	return firstErr
+}
+
+// inputWaiting returns the number of bytes received but not yet read.
+func (p *port) inputWaiting() (int, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return 0, ErrPortClosed
+	}
+	in, _, err := p.queues()
+	return in, err
+}
+
+func (p *port) lineErrors() (LineErrorCounts, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return LineErrorCounts{}, ErrPortClosed
+	}
+
+	errs, err := p.takeCommErrors(nil)
+	if err != nil {
+		return LineErrorCounts{}, err
+	}
+
+	var c LineErrorCounts
+	if errs&ceRxParity != 0 {
+		c.Parity = 1
+	}
+	if errs&ceFrame != 0 {
+		c.Framing = 1
+	}
+	if errs&(ceOverrun|ceRxOver) != 0 {
+		c.Overrun = 1
+	}
+	if errs&ceBreak != 0 {
+		c.Break = 1
+	}
+	return c, nil
+}
+
+// outputWaiting returns the number of bytes written but not yet transmitted.
+func (p *port) outputWaiting() (int, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return 0, ErrPortClosed
+	}
+	_, out, err := p.queues()
+	return out, err
+}
+
+func (p *port) modemStatus() (ModemStatus, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return ModemStatus{}, ErrPortClosed
+	}
+
+	var lines uint32
+	if err := getCommModemStatus(p.handle, &lines); err != nil {
+		return ModemStatus{}, disconnectErr(err)
+	}
+
+	return ModemStatus{
+		CTS: lines&msCTSOn != 0,
+		DSR: lines&msDSROn != 0,
+		RI:  lines&msRingOn != 0,
+		DCD: lines&msRLSDOn != 0,
+	}, nil
+}
+
+// waitModemChange waits for a modem status line to change, or ctx to be done.
+func (p *port) waitModemChange(ctx context.Context) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	_, err := p.waitCommEvent(ctx, evCTS|evDSR|evRLSD|evRing)
+	return err
+}
+
+// eventWatch is unused on Windows, where WaitCommEvent keeps track of events.
+type eventWatch struct{}
+
+// waitEvents waits for bytes to arrive, a line error or a break, or ctx to be
+// done, and returns the events that happened.
+func (p *port) waitEvents(ctx context.Context, w *eventWatch) ([]Event, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	mask, err := p.waitCommEvent(ctx, evRxChar|evErr|evBreak)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	if mask&evRxChar != 0 {
+		events = append(events, EventDataAvailable)
+	}
+	if mask&evErr != 0 {
+		events = append(events, EventLineError)
+	}
+	if mask&evBreak != 0 {
+		events = append(events, EventBreakReceived)
+	}
+	return events, nil
+}
+
+// waitCommEvent waits for one of the events in mask, or ctx to be done, and
+// returns the events that happened. It must be called with p.mut read locked.
+func (p *port) waitCommEvent(ctx context.Context, mask uint32) (uint32, error) {
+	for {
+		happened, err := p.waitCommEventOnce(ctx, mask)
+		if err != errReconfiguring {
+			return happened, err
+		}
+		p.yield()
+	}
+}
+
+// errReconfiguring is returned by waitCommEventOnce if it stopped waiting so a
+// Reconfigure could apply.
+var errReconfiguring = errors.New("serial: reconfiguring")
+
+func (p *port) waitCommEventOnce(ctx context.Context, mask uint32) (uint32, error) {
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return 0, ErrPortClosed
+	}
+
+	if err := setCommMask(p.handle, mask); err != nil {
+		return 0, disconnectErr(err)
+	}
+
+	o, err := newOverlapped()
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(o.HEvent)
+
+	var happened uint32
+	if err := waitCommEvent(p.handle, &happened, o); err != nil && err != windows.ERROR_IO_PENDING {
+		return 0, disconnectErr(err)
+	}
+
+	for {
+		ev, err := windows.WaitForSingleObject(o.HEvent, tickResolution)
+		if err != nil {
+			return 0, err
+		}
+
+		var done uint32
+		switch {
+		case ev == windows.WAIT_OBJECT_0:
+			if err := windows.GetOverlappedResult(p.handle, o, &done, false); err != nil {
+				if err == windows.ERROR_OPERATION_ABORTED {
+					return 0, ErrPortClosed
+				}
+				return 0, disconnectErr(err)
+			}
+			return happened, nil
+		case ctx.Err() != nil:
+			windows.CancelIoEx(p.handle, o)
+			windows.GetOverlappedResult(p.handle, o, &done, true)
+			return 0, ctx.Err()
+		case p.isClosing():
+			// Close may have canceled I/O before the wait began
+			windows.CancelIoEx(p.handle, o)
+			windows.GetOverlappedResult(p.handle, o, &done, true)
+			return 0, ErrPortClosed
+		case p.reconfiguring.Load():
+			windows.CancelIoEx(p.handle, o)
+			windows.GetOverlappedResult(p.handle, o, &done, true)
+			return 0, errReconfiguring
+		}
+	}
+}
+
+// Config returns the configuration the port was opened with, as applied.
+func (p *port) Config() (Config, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return Config{}, ErrPortClosed
+	}
+	return p.conf, nil
+}
+
+// UARTType returns ErrUnsupported, Windows does not report the UART type.
+func (p *port) UARTType() (string, error) {
+	return "", ErrUnsupported
+}
+
+// DCB returns the port's current device control block, for settings this
+// package does not model. Modify it and apply it with SetDCB. This is an
+// advanced, Windows-only escape hatch.
+func (p *port) DCB() (*DCB, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return nil, ErrPortClosed
+	}
+
+	var d DCB
+	if err := getCommState(p.handle, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// SetDCB applies d to the port. Settings made through it are not reflected in
+// the port's Config, and are lost on Reopen.
+func (p *port) SetDCB(d *DCB) error {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return ErrPortClosed
+	}
+
+	d.DCBLength = uint32(unsafe.Sizeof(*d))
+	return setCommState(p.handle, d)
+}
+
+// CommStatus returns the port's communications status. Retrieving the status
+// clears any pending communications errors. Its Errors include those cleared
+// by reads and queue queries since CommStatus, ClearErrors or LineErrors last
+// returned them.
+func (p *port) CommStatus() (CommStatus, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return CommStatus{}, ErrPortClosed
+	}
+
+	var cs comstat
+	errs, err := p.takeCommErrors(&cs)
+	if err != nil {
+		return CommStatus{}, err
+	}
+
+	return CommStatus{
+		CTSHold:  cs.Flags&comstatfCTSHold != 0,
+		DSRHold:  cs.Flags&comstatfDSRHold != 0,
+		RLSDHold: cs.Flags&comstatfRLSDHold != 0,
+		XoffHold: cs.Flags&comstatfXoffHold != 0,
+		XoffSent: cs.Flags&comstatfXoffSent != 0,
+		EOF:      cs.Flags&comstatfEOF != 0,
+		Txim:     cs.Flags&comstatfTxim != 0,
+
+		InQueue:  int(cs.CbInQue),
+		OutQueue: int(cs.CbOutQue),
+
+		Errors: commErrors(errs),
+	}, nil
+}
+
+// ClearErrors clears any pending communications errors, returning those that
+// were present, including those cleared by reads and queue queries since they
+// were last returned. Some drivers stop reading after an error until it is
+// cleared.
+func (p *port) ClearErrors() (CommErrors, error) {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	if p.isClosing() || p.handle == windows.InvalidHandle {
+		return CommErrors{}, ErrPortClosed
+	}
+
+	errs, err := p.takeCommErrors(nil)
+	if err != nil {
+		return CommErrors{}, err
+	}
+	return commErrors(errs), nil
+}
+
+func commErrors(errs uint32) CommErrors {
+	return CommErrors{
+		Break:    errs&ceBreak != 0,
+		Frame:    errs&ceFrame != 0,
+		Overrun:  errs&ceOverrun != 0,
+		RxOver:   errs&ceRxOver != 0,
+		RxParity: errs&ceRxParity != 0,
+		TxFull:   errs&ceTxFull != 0,
+	}
+}
+
+func dcbInit(d *DCB) {
+	d.Flags |= dcbfBinary // enable binary mode
+
+	// disable hardware flow control
+	d.Flags &^= dcbfOutxCTSFlow
+	d.Flags &^= dcbfOutxDSRFlow
+	d.Flags &^= dcbfDTRControl
+	d.Flags &^= dcbfRTSControl
+
+	// disable software flow control
+	d.Flags &^= dcbfOutX
+	d.Flags &^= dcbfInX
 
 	// disable replacement of bytes with parity errors
 	d.Flags &^= dcbfErrorChar
@@ -368,16 +1617,172 @@ func dcbInit(d *dcb) {
 	d.Flags &^= dcbfNull
 }
 
-func dcbSetBaudRate(d *dcb, baudRate int) error {
+// dcbSetFlowControl sets the flow control mode, which dcbInit disables. It
+// must be called after dcbSetLineLevels, as RTS/CTS flow control takes over
+// RTS. Under software flow control, xon and xoff replace DC1 and DC3 if not
+// zero.
+func dcbSetFlowControl(d *DCB, fc FlowControl, xon, xoff byte) error {
+	switch fc {
+	case FlowControlNone, FlowControlNil: // default
+		// dcbInit disables flow control
+	case FlowControlRTSCTS:
+		d.Flags |= dcbfOutxCTSFlow
+		d.Flags &^= dcbfRTSControl
+		d.Flags |= rtsControlHandshake << 12
+	case FlowControlXONXOFF:
+		d.Flags |= dcbfOutX | dcbfInX
+		d.XonChar = int8(orDefault(xon, DefaultXonChar))
+		d.XoffChar = int8(orDefault(xoff, DefaultXoffChar))
+	default:
+		return fmt.Errorf("unsupported flow control: %v", fc)
+	}
+
+	return nil
+}
+
+// dcbSetFlowControlLimits sets the input buffer watermarks that are not
+// zero.
+func dcbSetFlowControlLimits(d *DCB, xonLim, xoffLim int) {
+	if xonLim != 0 {
+		d.XonLim = uint16(xonLim)
+	}
+	if xoffLim != 0 {
+		d.XoffLim = uint16(xoffLim)
+	}
+}
+
+// dcbSetLineLevels sets DTR and RTS control, which dcbInit disables, for the
+// lines to be at dtr and rts, LineLevelNil keeping the control in origFlags,
+// the flags before dcbInit.
+func dcbSetLineLevels(d *DCB, origFlags uint32, dtr, rts LineLevel) {
+	switch dtr {
+	case LineLevelNil:
+		d.Flags |= origFlags & dcbfDTRControl
+	case LineLevelHigh:
+		d.Flags |= dtrControlEnable << 4
+	}
+
+	switch rts {
+	case LineLevelNil:
+		d.Flags |= origFlags & dcbfRTSControl
+	case LineLevelHigh:
+		d.Flags |= rtsControlEnable << 12
+	}
+}
+
+// escapeLineLevels drives DTR and RTS to dtr and rts directly, as some
+// drivers only act on the DCB control settings once the lines are next
+// toggled. Lines at LineLevelNil are left alone, as is RTS under RTS/CTS flow
+// control, where the driver drives it.
+func escapeLineLevels(handle windows.Handle, dtr, rts LineLevel, fc FlowControl) error {
+	if err := escapeLineLevel(handle, dtr, setDTR, clrDTR); err != nil {
+		return err
+	}
+	if fc == FlowControlRTSCTS {
+		return nil
+	}
+	return escapeLineLevel(handle, rts, setRTS, clrRTS)
+}
+
+func escapeLineLevel(handle windows.Handle, level LineLevel, set, clr uint32) error {
+	switch level {
+	case LineLevelHigh:
+		return escapeCommFunction(handle, set)
+	case LineLevelLow:
+		return escapeCommFunction(handle, clr)
+	}
+	return nil
+}
+
+// setCommTimeouts makes reads return after readTimeout and writes after
+// writeTimeout milliseconds at most, so deadlines and Close are noticed.
+func setCommTimeouts(handle windows.Handle, readTimeout, writeTimeout uint32) error {
+	var ct windows.CommTimeouts
+
+	if err := windows.GetCommTimeouts(handle, &ct); err != nil {
+		return disconnectErr(err)
+	}
+
+	ct.ReadIntervalTimeout = 0
+	ct.ReadTotalTimeoutMultiplier = 0
+	ct.ReadTotalTimeoutConstant = readTimeout
+	ct.WriteTotalTimeoutMultiplier = 0
+	ct.WriteTotalTimeoutConstant = writeTimeout
+
+	return disconnectErr(windows.SetCommTimeouts(handle, &ct))
+}
+
+// commTimeout returns the COMMTIMEOUTS total timeout, in milliseconds, for an
+// operation that must return by deadline: tickResolution, or less if the
+// deadline is sooner, so deadlines are honored to the millisecond.
+func commTimeout(deadline time.Time) uint32 {
+	// no deadline must be handled before any arithmetic: the time until the
+	// zero Time is hugely negative and would read as an expired deadline
+	if deadline.IsZero() {
+		return tickResolution
+	}
+
+	ms := time.Until(deadline).Milliseconds()
+	switch {
+	case ms < 1:
+		return 1
+	case ms > tickResolution:
+		return tickResolution
+	}
+	return uint32(ms)
+}
+
+// setIOTimeout sets timeout, p.readTimeout or p.writeTimeout, so the next
+// operation returns by deadline. COMMTIMEOUTS is only updated if the timeout
+// changes.
+func (p *port) setIOTimeout(timeout *uint32, deadline time.Time) error {
+	p.timeoutsMut.Lock()
+	defer p.timeoutsMut.Unlock()
+
+	ms := commTimeout(deadline)
+	if *timeout == ms {
+		return nil
+	}
+
+	prev := *timeout
+	*timeout = ms
+	if err := setCommTimeouts(p.handle, p.readTimeout, p.writeTimeout); err != nil {
+		*timeout = prev
+		return err
+	}
+	return nil
+}
+
+// disconnectErr wraps the errors drivers return once their device has been
+// removed as ErrPortDisconnected. Other errors are returned as is.
+func disconnectErr(err error) error {
+	switch err {
+	case windows.ERROR_DEVICE_REMOVED, windows.ERROR_DEVICE_NOT_CONNECTED,
+		windows.ERROR_BAD_COMMAND, windows.ERROR_GEN_FAILURE:
+		// usbser.sys and most USB serial drivers fail I/O with
+		// ERROR_BAD_COMMAND or ERROR_GEN_FAILURE once unplugged
+		return &disconnectedError{err}
+	}
+	return err
+}
+
+// dcbSetBaudRate sets baudRate, which need not be a standard rate: the DCB
+// takes rates as is, leaving SetCommState to reject those the driver does not
+// support.
+func dcbSetBaudRate(d *DCB, baudRate int) error {
 	if rate, ok := baudRates[baudRate]; ok {
 		d.BaudRate = rate
 		return nil
 	}
+	if baudRate > 0 && uint64(baudRate) <= math.MaxUint32 {
+		d.BaudRate = uint32(baudRate)
+		return nil
+	}
 
-	return fmt.Errorf("unsupported baud rate: %d", baudRate)
+	return fmt.Errorf("%w: %d", ErrUnsupportedBaudRate, baudRate)
 }
 
-func dcbSetByteSize(d *dcb, byteSize int) error {
+func dcbSetByteSize(d *DCB, byteSize int) error {
 	switch byteSize {
 	case 8, 0: // default
 		d.ByteSize = 8
@@ -394,7 +1799,7 @@ func dcbSetByteSize(d *dcb, byteSize int) error {
 	return nil
 }
 
-func dcbSetStopBits(d *dcb, stopBits StopBits) error {
+func dcbSetStopBits(d *DCB, stopBits StopBits) error {
 	switch stopBits {
 	case StopBits1, StopBitsNil: // default
 		d.StopBits = oneStopBit
@@ -407,7 +1812,7 @@ func dcbSetStopBits(d *dcb, stopBits StopBits) error {
 	return nil
 }
 
-func dcbSetParity(d *dcb, parity Parity) error {
+func dcbSetParity(d *DCB, parity Parity) error {
 	switch parity {
 	case ParityNone:
 		d.Flags &^= dcbfParity
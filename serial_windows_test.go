@@ -4,6 +4,14 @@ package serial_test
 
 import "testing"
 
-func setupLoopbackPorts(t *testing.T) (string, string) {
+func setupLoopbackPorts(t testing.TB) (string, string) {
 	return "COM5", "COM6"
 }
+
+// skipIfNoModemLines does nothing, as serial ports on Windows always have modem
+// lines.
+func skipIfNoModemLines(t *testing.T, err error) {}
+
+// skipIfNoErrorCounts does nothing, as ClearCommError reports line errors on
+// every serial port.
+func skipIfNoErrorCounts(t *testing.T, err error) {}
@@ -0,0 +1,121 @@
+package serial
+
+import "io"
+
+// SLIP special bytes, RFC 1055.
+const (
+	slipEnd    = 0xc0
+	slipEsc    = 0xdb
+	slipEscEnd = 0xdc
+	slipEscEsc = 0xdd
+)
+
+// slipMaxLength bounds a decoded packet in bytes. RFC 1055 suggests 1006, but
+// nothing in the framing limits it.
+const slipMaxLength = 4096
+
+// SLIPWriter writes packets to a Port framed per RFC 1055 (SLIP).
+type SLIPWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewSLIPWriter returns a SLIPWriter that writes packets to w, typically a
+// Port.
+func NewSLIPWriter(w io.Writer) *SLIPWriter {
+	return &SLIPWriter{w: w}
+}
+
+// WritePacket writes packet with END and ESC bytes escaped, between END
+// delimiters, in a single Write. The leading END flushes any line noise
+// received by the other end before the packet.
+func (s *SLIPWriter) WritePacket(packet []byte) error {
+	s.buf = append(s.buf[:0], slipEnd)
+	for _, b := range packet {
+		switch b {
+		case slipEnd:
+			s.buf = append(s.buf, slipEsc, slipEscEnd)
+		case slipEsc:
+			s.buf = append(s.buf, slipEsc, slipEscEsc)
+		default:
+			s.buf = append(s.buf, b)
+		}
+	}
+	s.buf = append(s.buf, slipEnd)
+
+	_, err := s.w.Write(s.buf)
+	return err
+}
+
+// SLIPReader reads packets framed per RFC 1055 (SLIP) from a Port.
+type SLIPReader struct {
+	r       io.Reader
+	buf     []byte // packet decoded so far, kept across failed reads
+	esc     bool   // the last byte read was ESC
+	discard bool   // the packet is too long and is being skipped
+	b       [1]byte
+}
+
+// NewSLIPReader returns a SLIPReader that reads packets from r, typically a
+// Port.
+func NewSLIPReader(r io.Reader) *SLIPReader {
+	return &SLIPReader{r: r, buf: make([]byte, 0, slipMaxLength)}
+}
+
+// ReadPacket returns the next non-empty packet, unescaped. Packets longer
+// than slipMaxLength are discarded. An ESC followed by anything other than
+// ESC_END or ESC_ESC is a protocol violation; as RFC 1055 suggests, the byte
+// following it is kept as is.
+//
+// If reading fails partway through a packet, e.g. because the read deadline
+// expired, the error is returned and the partial packet is kept, to be
+// completed by the next call.
+func (s *SLIPReader) ReadPacket() ([]byte, error) {
+	for {
+		c, err := s.r.Read(s.b[:])
+		if c == 1 {
+			if packet := s.decode(s.b[0]); packet != nil {
+				return packet, nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// decode adds b to the packet being read, returning a copy of the packet once
+// it is complete.
+func (s *SLIPReader) decode(b byte) []byte {
+	if b == slipEnd {
+		packet := s.buf
+		discard := s.discard
+		s.buf, s.esc, s.discard = s.buf[:0], false, false
+
+		if len(packet) == 0 || discard {
+			return nil
+		}
+		return append([]byte(nil), packet...)
+	}
+
+	if s.esc {
+		s.esc = false
+		switch b {
+		case slipEscEnd:
+			b = slipEnd
+		case slipEscEsc:
+			b = slipEsc
+		}
+	} else if b == slipEsc {
+		s.esc = true
+		return nil
+	}
+
+	if len(s.buf) == slipMaxLength {
+		s.discard = true
+	}
+	if !s.discard {
+		s.buf = append(s.buf, b)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package serial_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/shasderias/serial"
+)
+
+func TestSLIPRoundTrip(t *testing.T) {
+	packets := [][]byte{
+		{0x01, 0xc0, 0x02, 0xdb, 0x03},
+		{0xdb, 0xdc},
+		{0xc0},
+	}
+
+	var buf bytes.Buffer
+	w := serial.NewSLIPWriter(&buf)
+	for _, p := range packets {
+		if err := w.WritePacket(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []byte{0xc0, 0x01, 0xdb, 0xdc, 0x02, 0xdb, 0xdd, 0x03, 0xc0}; !bytes.HasPrefix(buf.Bytes(), want) {
+		t.Fatalf("got % x; want prefix % x", buf.Bytes(), want)
+	}
+
+	r := serial.NewSLIPReader(&buf)
+	for _, want := range packets {
+		got, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got % x; want % x", got, want)
+		}
+	}
+}
+
+func TestSLIPReaderResumesAfterDeadline(t *testing.T) {
+	// the stall falls between an ESC and the byte it escapes
+	r := serial.NewSLIPReader(&stallingReader{data: "\xc0ab\xdb\xdccd\xc0", stalls: []int{4}})
+
+	if _, err := r.ReadPacket(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	got, err := r.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ab\xc0cd"; string(got) != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
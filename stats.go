@@ -0,0 +1,55 @@
+package serial
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// Stats are counters of a port's I/O since it was opened.
+type Stats struct {
+	BytesRead    uint64 // bytes read by any method
+	BytesWritten uint64 // bytes written by any method
+
+	Reads  uint64 // calls to Read and ReadContext
+	Writes uint64 // calls to Write, WriteContext and WriteString
+
+	ReadTimeouts  uint64 // Read and ReadContext calls ended by a deadline
+	WriteTimeouts uint64 // Write and WriteContext calls ended by a deadline
+}
+
+// stats holds the counters behind Stats. They are updated atomically, so
+// counting adds no contention to I/O.
+type stats struct {
+	bytesRead, bytesWritten     atomic.Uint64
+	reads, writes               atomic.Uint64
+	readTimeouts, writeTimeouts atomic.Uint64
+}
+
+// countRead counts a call to Read or ReadContext that returned err.
+func (s *stats) countRead(err error) {
+	s.reads.Add(1)
+	if err == os.ErrDeadlineExceeded {
+		s.readTimeouts.Add(1)
+	}
+}
+
+// countWrite counts a call to Write or WriteContext that returned err.
+func (s *stats) countWrite(err error) {
+	s.writes.Add(1)
+	if err == os.ErrDeadlineExceeded {
+		s.writeTimeouts.Add(1)
+	}
+}
+
+// Stats returns the port's I/O counters, e.g. for monitoring a long-running
+// gateway.
+func (p *port) Stats() Stats {
+	return Stats{
+		BytesRead:     p.stats.bytesRead.Load(),
+		BytesWritten:  p.stats.bytesWritten.Load(),
+		Reads:         p.stats.reads.Load(),
+		Writes:        p.stats.writes.Load(),
+		ReadTimeouts:  p.stats.readTimeouts.Load(),
+		WriteTimeouts: p.stats.writeTimeouts.Load(),
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !faultinject
+
+package serial
+
+import "golang.org/x/sys/unix"
+
+// sysOpen, sysRead and sysWrite are the system calls ports are opened, read
+// and written with, and sysGetICounter the ioctl their line errors are counted
+// with. Builds tagged faultinject replace them with hookable versions, see
+// fault_linux.go.
+
+func sysOpen(path string, mode int) (int, error) {
+	return unix.Open(path, mode, 0)
+}
+
+func sysRead(fd int, b []byte) (int, error) {
+	return unix.Read(fd, b)
+}
+
+func sysWrite(fd int, b []byte) (int, error) {
+	return unix.Write(fd, b)
+}
+
+func sysGetICounter(fd int) (serialICounter, error) {
+	return getICounter(fd)
+}
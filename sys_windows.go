@@ -0,0 +1,17 @@
+//go:build !faultinject
+
+package serial
+
+import "golang.org/x/sys/windows"
+
+// sysReadFile and sysWriteFile are the system calls ports read and write
+// with. Builds tagged faultinject replace them with hookable versions, see
+// fault_windows.go.
+
+func sysReadFile(handle windows.Handle, b []byte, done *uint32, o *windows.Overlapped) error {
+	return windows.ReadFile(handle, b, done, o)
+}
+
+func sysWriteFile(handle windows.Handle, b []byte, done *uint32, o *windows.Overlapped) error {
+	return windows.WriteFile(handle, b, done, o)
+}
@@ -4,5 +4,14 @@ package serial
 
 //go:generate mkwinsyscall -output zsyscall_windows.go $GOFILE
 
-//sys getCommState(handle windows.Handle, dcb *dcb) (err error) = GetCommState
-//sys setCommState(handle windows.Handle, dcb *dcb) (err error) = SetCommState
+//sys getCommState(handle windows.Handle, dcb *DCB) (err error) = GetCommState
+//sys setCommState(handle windows.Handle, dcb *DCB) (err error) = SetCommState
+//sys clearCommError(handle windows.Handle, errors *uint32, stat *comstat) (err error) = ClearCommError
+//sys escapeCommFunction(handle windows.Handle, function uint32) (err error) = EscapeCommFunction
+//sys getCommModemStatus(handle windows.Handle, stat *uint32) (err error) = GetCommModemStatus
+//sys setCommMask(handle windows.Handle, mask uint32) (err error) = SetCommMask
+//sys waitCommEvent(handle windows.Handle, mask *uint32, overlapped *windows.Overlapped) (err error) = WaitCommEvent
+//sys purgeComm(handle windows.Handle, flags uint32) (err error) = PurgeComm
+//sys transmitCommChar(handle windows.Handle, char byte) (err error) = TransmitCommChar
+//sys getCommProperties(handle windows.Handle, prop *commProp) (err error) = GetCommProperties
+//sys setupComm(handle windows.Handle, inQueue uint32, outQueue uint32) (err error) = SetupComm
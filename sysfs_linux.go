@@ -0,0 +1,65 @@
+package serial
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const sysClassTTY = "/sys/class/tty"
+
+// ttyName resolves path, which may be a symlink such as a
+// /dev/serial/by-id entry, to the kernel name of the tty, e.g. ttyUSB0.
+func ttyName(path string) (string, error) {
+	dev, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(dev), nil
+}
+
+// ttyDriver returns the name of the driver bound to the named tty, e.g.
+// cdc_acm, or "" if there is none, as is the case for ptys.
+func ttyDriver(name string) string {
+	link, err := os.Readlink(filepath.Join(sysClassTTY, name, "device", "driver"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(link)
+}
+
+// isCDCACM reports whether path is a USB CDC-ACM device (ttyACM*), whose
+// line settings are meaningless as the USB transport is fixed.
+func isCDCACM(path string) bool {
+	name, err := ttyName(path)
+	if err != nil {
+		return false
+	}
+	return ttyDriver(name) == "cdc_acm"
+}
+
+// fdPath returns the path of the file fd refers to, as the kernel sees it.
+func fdPath(fd int) (string, error) {
+	return os.Readlink(filepath.Join("/proc/self/fd", strconv.Itoa(fd)))
+}
+
+// ttyDevice returns the sysfs node of the hardware behind the named tty, e.g.
+// /sys/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0 for a USB adapter.
+func ttyDevice(name string) (string, error) {
+	dev, err := filepath.EvalSymlinks(filepath.Join(sysClassTTY, name, "device"))
+	if err != nil {
+		return "", fmt.Errorf("no sysfs device for %s: %w", name, err)
+	}
+	return dev, nil
+}
+
+// setRxTrigger sets the receive FIFO trigger level of the named tty through
+// the rx_trig_bytes attribute of 8250 family drivers.
+func setRxTrigger(name string, bytes int) error {
+	attr := filepath.Join(sysClassTTY, name, "rx_trig_bytes")
+	if _, err := os.Stat(attr); err != nil {
+		return fmt.Errorf("receive FIFO trigger: %w", ErrUnsupported)
+	}
+	return os.WriteFile(attr, []byte(strconv.Itoa(bytes)), 0)
+}
@@ -0,0 +1,64 @@
+package serial
+
+import (
+	"io"
+	"sync"
+)
+
+// tapBufferSize bounds the bytes waiting to be copied to a tap writer.
+const tapBufferSize = 64 * 1024
+
+// tap copies bytes to w without blocking the caller. Bytes are queued and
+// written by a goroutine that runs while the queue is non-empty; bytes that
+// would grow the queue past tapBufferSize are dropped.
+type tap struct {
+	w io.Writer
+
+	mut     sync.Mutex
+	buf     []byte
+	running bool
+}
+
+// newTap returns a tap copying to w, or nil if w is nil. A nil *tap discards
+// everything.
+func newTap(w io.Writer) *tap {
+	if w == nil {
+		return nil
+	}
+	return &tap{w: w}
+}
+
+func (t *tap) write(b []byte) {
+	if t == nil || len(b) == 0 {
+		return
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if len(t.buf)+len(b) > tapBufferSize {
+		return // w is not keeping up
+	}
+	t.buf = append(t.buf, b...)
+
+	if !t.running {
+		t.running = true
+		go t.run()
+	}
+}
+
+func (t *tap) run() {
+	for {
+		t.mut.Lock()
+		b := t.buf
+		t.buf = nil
+		if len(b) == 0 {
+			t.running = false
+			t.mut.Unlock()
+			return
+		}
+		t.mut.Unlock()
+
+		t.w.Write(b)
+	}
+}
@@ -0,0 +1,45 @@
+package serial
+
+import (
+	"sync"
+	"time"
+)
+
+// Transactor serializes request/response exchanges on a Port shared by
+// multiple goroutines, so one goroutine's request and response are not
+// interleaved with another's.
+type Transactor struct {
+	p   Port
+	mut sync.Mutex
+
+	lastActive    time.Time // when the last transaction finished
+	lastActiveMut sync.Mutex
+}
+
+// NewTransactor returns a Transactor for p. Once p is shared through a
+// Transactor, all I/O on p should go through Transact.
+func NewTransactor(p Port) *Transactor {
+	return &Transactor{p: p, lastActive: time.Now()}
+}
+
+// Transact calls f with exclusive access to the Port and returns its error.
+// f typically writes a request then reads the response.
+func (t *Transactor) Transact(f func(p Port) error) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	defer func() {
+		t.lastActiveMut.Lock()
+		t.lastActive = time.Now()
+		t.lastActiveMut.Unlock()
+	}()
+
+	return f(t.p)
+}
+
+func (t *Transactor) lastActivity() time.Time {
+	t.lastActiveMut.Lock()
+	defer t.lastActiveMut.Unlock()
+
+	return t.lastActive
+}
@@ -0,0 +1,62 @@
+package serial_test
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shasderias/serial"
+)
+
+func TestTransactorSerializes(t *testing.T) {
+	port1, port2 := getTestPorts(t)
+	defer port1.Close()
+	defer port2.Close()
+
+	// port2 plays the device, echoing every byte back
+	go func() {
+		b := make([]byte, 1)
+		for {
+			if _, err := port2.Read(b); err != nil {
+				return
+			}
+			if _, err := port2.Write(b); err != nil {
+				return
+			}
+		}
+	}()
+
+	tr := serial.NewTransactor(port1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			req := []byte(fmt.Sprintf("request %d", i))
+			resp := make([]byte, len(req))
+
+			err := tr.Transact(func(p serial.Port) error {
+				if _, err := p.Write(req); err != nil {
+					return err
+				}
+				if err := p.SetReadDeadline(time.Now().Add(longSleepDuration)); err != nil {
+					return err
+				}
+				_, err := p.Read(resp)
+				return err
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(resp, req) {
+				t.Errorf("got response %q; want %q", resp, req)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
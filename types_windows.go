@@ -31,6 +31,27 @@ const (
 	cbr256000 = C.CBR_256000
 )
 
+// https://learn.microsoft.com/en-us/windows/win32/api/winbase/ns-winbase-commprop
+
+const (
+	baud110    = C.BAUD_110
+	baud300    = C.BAUD_300
+	baud600    = C.BAUD_600
+	baud1200   = C.BAUD_1200
+	baud2400   = C.BAUD_2400
+	baud4800   = C.BAUD_4800
+	baud9600   = C.BAUD_9600
+	baud14400  = C.BAUD_14400
+	baud19200  = C.BAUD_19200
+	baud38400  = C.BAUD_38400
+	baud128K   = C.BAUD_128K
+	baud115200 = C.BAUD_115200
+	baud57600  = C.BAUD_57600
+	baudUser   = C.BAUD_USER
+)
+
+type commProp C.COMMPROP
+
 const (
 	dtrControlDisable   = C.DTR_CONTROL_DISABLE
 	dtrControlEnable    = C.DTR_CONTROL_ENABLE
@@ -44,6 +65,49 @@ const (
 	rtsControlToggle    = C.RTS_CONTROL_TOGGLE
 )
 
+// https://learn.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-escapecommfunction
+
+// https://learn.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-setcommmask
+
+const (
+	evRxChar = C.EV_RXCHAR
+	evCTS    = C.EV_CTS
+	evDSR    = C.EV_DSR
+	evRLSD   = C.EV_RLSD
+	evBreak  = C.EV_BREAK
+	evErr    = C.EV_ERR
+	evRing   = C.EV_RING
+)
+
+// https://learn.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-getcommmodemstatus
+
+const (
+	msCTSOn  = C.MS_CTS_ON
+	msDSROn  = C.MS_DSR_ON
+	msRingOn = C.MS_RING_ON
+	msRLSDOn = C.MS_RLSD_ON
+)
+
+// https://learn.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-purgecomm
+
+const (
+	purgeTxAbort = C.PURGE_TXABORT
+	purgeRxAbort = C.PURGE_RXABORT
+	purgeTxClear = C.PURGE_TXCLEAR
+	purgeRxClear = C.PURGE_RXCLEAR
+)
+
+const (
+	setXON = C.SETXON
+	setRTS = C.SETRTS
+	clrRTS = C.CLRRTS
+	setDTR = C.SETDTR
+	clrDTR = C.CLRDTR
+
+	setBreak = C.SETBREAK
+	clrBreak = C.CLRBREAK
+)
+
 const (
 	oneStopBit  = C.ONESTOPBIT
 	twoStopBits = C.TWOSTOPBITS
@@ -55,7 +119,20 @@ const (
 	noParity   = C.NOPARITY
 )
 
-type dcb C.DCB
+type DCB C.DCB
+
+// https://learn.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-clearcommerror
+
+const (
+	ceRxOver   = C.CE_RXOVER
+	ceOverrun  = C.CE_OVERRUN
+	ceRxParity = C.CE_RXPARITY
+	ceFrame    = C.CE_FRAME
+	ceBreak    = C.CE_BREAK
+	ceTxFull   = C.CE_TXFULL
+)
+
+type comstat C.COMSTAT
 
 func toDWORD(val int) C.DWORD {
 	return C.DWORD(val)
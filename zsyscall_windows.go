@@ -40,11 +40,52 @@ func errnoErr(e syscall.Errno) error {
 var (
 	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
 
-	procGetCommState = modkernel32.NewProc("GetCommState")
-	procSetCommState = modkernel32.NewProc("SetCommState")
+	procClearCommError     = modkernel32.NewProc("ClearCommError")
+	procEscapeCommFunction = modkernel32.NewProc("EscapeCommFunction")
+	procGetCommModemStatus = modkernel32.NewProc("GetCommModemStatus")
+	procGetCommProperties  = modkernel32.NewProc("GetCommProperties")
+	procGetCommState       = modkernel32.NewProc("GetCommState")
+	procPurgeComm          = modkernel32.NewProc("PurgeComm")
+	procSetCommMask        = modkernel32.NewProc("SetCommMask")
+	procSetCommState       = modkernel32.NewProc("SetCommState")
+	procSetupComm          = modkernel32.NewProc("SetupComm")
+	procTransmitCommChar   = modkernel32.NewProc("TransmitCommChar")
+	procWaitCommEvent      = modkernel32.NewProc("WaitCommEvent")
 )
 
-func getCommState(handle windows.Handle, dcb *dcb) (err error) {
+func clearCommError(handle windows.Handle, errors *uint32, stat *comstat) (err error) {
+	r1, _, e1 := syscall.Syscall(procClearCommError.Addr(), 3, uintptr(handle), uintptr(unsafe.Pointer(errors)), uintptr(unsafe.Pointer(stat)))
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func escapeCommFunction(handle windows.Handle, function uint32) (err error) {
+	r1, _, e1 := syscall.Syscall(procEscapeCommFunction.Addr(), 2, uintptr(handle), uintptr(function), 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func getCommModemStatus(handle windows.Handle, stat *uint32) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetCommModemStatus.Addr(), 2, uintptr(handle), uintptr(unsafe.Pointer(stat)), 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func getCommProperties(handle windows.Handle, prop *commProp) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetCommProperties.Addr(), 2, uintptr(handle), uintptr(unsafe.Pointer(prop)), 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func getCommState(handle windows.Handle, dcb *DCB) (err error) {
 	r1, _, e1 := syscall.Syscall(procGetCommState.Addr(), 2, uintptr(handle), uintptr(unsafe.Pointer(dcb)), 0)
 	if r1 == 0 {
 		err = errnoErr(e1)
@@ -52,10 +93,50 @@ func getCommState(handle windows.Handle, dcb *dcb) (err error) {
 	return
 }
 
-func setCommState(handle windows.Handle, dcb *dcb) (err error) {
+func purgeComm(handle windows.Handle, flags uint32) (err error) {
+	r1, _, e1 := syscall.Syscall(procPurgeComm.Addr(), 2, uintptr(handle), uintptr(flags), 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func setCommMask(handle windows.Handle, mask uint32) (err error) {
+	r1, _, e1 := syscall.Syscall(procSetCommMask.Addr(), 2, uintptr(handle), uintptr(mask), 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func setCommState(handle windows.Handle, dcb *DCB) (err error) {
 	r1, _, e1 := syscall.Syscall(procSetCommState.Addr(), 2, uintptr(handle), uintptr(unsafe.Pointer(dcb)), 0)
 	if r1 == 0 {
 		err = errnoErr(e1)
 	}
 	return
 }
+
+func setupComm(handle windows.Handle, inQueue uint32, outQueue uint32) (err error) {
+	r1, _, e1 := syscall.Syscall(procSetupComm.Addr(), 3, uintptr(handle), uintptr(inQueue), uintptr(outQueue))
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func transmitCommChar(handle windows.Handle, char byte) (err error) {
+	r1, _, e1 := syscall.Syscall(procTransmitCommChar.Addr(), 2, uintptr(handle), uintptr(char), 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func waitCommEvent(handle windows.Handle, mask *uint32, overlapped *windows.Overlapped) (err error) {
+	r1, _, e1 := syscall.Syscall(procWaitCommEvent.Addr(), 3, uintptr(handle), uintptr(unsafe.Pointer(mask)), uintptr(unsafe.Pointer(overlapped)))
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}